@@ -0,0 +1,80 @@
+package entitas
+
+import (
+	"reflect"
+)
+
+// TypeOf returns the component type index context registered for T, the
+// pointer type passed to RegisterComponent.
+func TypeOf[T Component](context Context) (int, bool) {
+	return context.ComponentType(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// Create returns a component of type T from the pool of context.
+func Create[T Component](context Context) (T, error) {
+	var zero T
+	t, ok := TypeOf[T](context)
+	if !ok {
+		return zero, ErrComponentNotRegistered
+	}
+	return context.CreateComponent(t).(T), nil
+}
+
+// GetUnique returns the unique component of type T of context.
+func GetUnique[T Component](context Context) (T, error) {
+	var zero T
+	t, ok := TypeOf[T](context)
+	if !ok {
+		return zero, ErrComponentNotRegistered
+	}
+	c, err := context.Unique(t)
+	if err != nil {
+		return zero, err
+	}
+	return c.(T), nil
+}
+
+func Get[T Component](e Entity) (T, error) {
+	var zero T
+	t, ok := TypeOf[T](e.Context())
+	if !ok {
+		return zero, ErrComponentNotRegistered
+	}
+	c, err := e.Component(t)
+	if err != nil {
+		return zero, err
+	}
+	return c.(T), nil
+}
+
+func TryGet[T Component](e Entity) (T, bool) {
+	c, err := Get[T](e)
+	return c, err == nil
+}
+
+func Has[T Component](e Entity) bool {
+	t, ok := TypeOf[T](e.Context())
+	return ok && e.HasComponent(t)
+}
+
+func Add[T Component](e Entity, c T) error {
+	if _, ok := TypeOf[T](e.Context()); !ok {
+		return ErrComponentNotRegistered
+	}
+	return e.AddComponent(c)
+}
+
+func Replace[T Component](e Entity, c T) error {
+	if _, ok := TypeOf[T](e.Context()); !ok {
+		return ErrComponentNotRegistered
+	}
+	return e.UpdateComponent(c)
+}
+
+func Remove[T Component](e Entity) error {
+	t, ok := TypeOf[T](e.Context())
+	if !ok {
+		return ErrComponentNotRegistered
+	}
+	return e.RemoveComponent(t)
+}
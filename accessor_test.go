@@ -0,0 +1,50 @@
+package entitas
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestAccessor(t *testing.T) {
+	Convey("Given a context with registered components", t, func() {
+		context := NewContext(0, NumComponents)
+		context.RegisterComponent(&componentA{})
+		context.RegisterComponent(&componentB{})
+		e := context.CreateEntity()
+
+		Convey("Components are added and read back by Go type", func() {
+			So(Add(e, &componentA{1}), ShouldBeNil)
+			So(Has[*componentA](e), ShouldBeTrue)
+
+			a, err := Get[*componentA](e)
+			So(err, ShouldBeNil)
+			So(a.value, ShouldEqual, 1)
+
+			_, ok := TryGet[*componentB](e)
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("Components are replaced and removed by Go type", func() {
+			Add(e, &componentA{1})
+			So(Replace(e, &componentA{2}), ShouldBeNil)
+			a, _ := Get[*componentA](e)
+			So(a.value, ShouldEqual, 2)
+
+			So(Remove[*componentA](e), ShouldBeNil)
+			So(Remove[*componentA](e), ShouldEqual, ErrComponentDoesNotExist)
+		})
+
+		Convey("Unregistered types are reported", func() {
+			_, err := Get[*componentC](e)
+			So(err, ShouldEqual, ErrComponentNotRegistered)
+			So(Has[*componentC](e), ShouldBeFalse)
+			So(Add(e, &componentC{}), ShouldEqual, ErrComponentNotRegistered)
+		})
+
+		Convey("Components are created from the pool", func() {
+			b, err := Create[*componentB](context)
+			So(err, ShouldBeNil)
+			So(b, ShouldNotBeNil)
+		})
+	})
+}
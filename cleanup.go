@@ -0,0 +1,91 @@
+package entitas
+
+import "fmt"
+
+// CleanupMode tells the cleanup system what to do with the components of
+// a type at the end of every frame.
+type CleanupMode uint
+
+const (
+	CleanupNone CleanupMode = iota
+	// CleanupRemoveComponent removes the component from its entities.
+	CleanupRemoveComponent
+	// CleanupDestroyEntity destroys every entity holding the component.
+	CleanupDestroyEntity
+)
+
+// SetComponentCleanup registers component type t for the cleanup system,
+// which suits one-frame event and flag components.
+func (p *context) SetComponentCleanup(t int, mode CleanupMode) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if t < 0 || t >= p.totalComponents {
+		panic(fmt.Sprintf("component type %d out of range [0, %d)", t, p.totalComponents))
+	}
+	p.lock.beginWrite()
+	p.cleanups[t] = mode
+	p.lock.endWrite()
+}
+
+func (p *context) ComponentCleanup(t int) CleanupMode {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.cleanups[t]
+}
+
+type cleanupSystem struct {
+	context Context
+	groups  []Group
+}
+
+// NewCleanupSystem returns a system that applies the cleanup mode of every
+// component type in its Cleanup phase. Add it last to the root Systems, so
+// it runs once all other systems of the frame are done.
+func NewCleanupSystem() System {
+	return &cleanupSystem{}
+}
+
+func (s *cleanupSystem) Initialize(context Context) {
+	s.context = context
+	s.groups = make([]Group, context.TotalComponents())
+}
+
+func (s *cleanupSystem) Cleanup() {
+	for t, g := range s.groups {
+		mode := s.context.ComponentCleanup(t)
+		if mode == CleanupNone {
+			if g != nil {
+				s.context.ReleaseGroup(g)
+				s.groups[t] = nil
+			}
+			continue
+		}
+		if g == nil {
+			g = s.context.Group(AllOf(t))
+			s.groups[t] = g
+		}
+
+		for _, e := range g.Entities() {
+			if !e.IsAlive() {
+				continue
+			}
+			switch mode {
+			case CleanupRemoveComponent:
+				e.RemoveComponent(t)
+			case CleanupDestroyEntity:
+				e.Destroy()
+			}
+		}
+	}
+}
+
+func (s *cleanupSystem) TearDown() {
+	for t, g := range s.groups {
+		if g != nil {
+			s.context.ReleaseGroup(g)
+			s.groups[t] = nil
+		}
+	}
+}
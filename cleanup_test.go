@@ -0,0 +1,38 @@
+package entitas
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestCleanupSystem(t *testing.T) {
+	Convey("Given cleanup modes for components A and B", t, func() {
+		context := NewContext(0, NumComponents)
+		context.SetComponentCleanup(ComponentA, CleanupRemoveComponent)
+		context.SetComponentCleanup(ComponentB, CleanupDestroyEntity)
+		systems := NewSystems("root").Add(NewCleanupSystem())
+		systems.Initialize(context)
+
+		e1 := context.CreateEntity(NewComponentA(1), NewComponentC())
+		e2 := context.CreateEntity(NewComponentA(2), NewComponentB(0))
+		e3 := context.CreateEntity(NewComponentC())
+
+		Convey("Cleanup removes and destroys in one pass", func() {
+			systems.Cleanup()
+			So(e1.IsAlive(), ShouldBeTrue)
+			So(e1.HasComponent(ComponentA), ShouldBeFalse)
+			So(e1.HasComponent(ComponentC), ShouldBeTrue)
+			So(e2.IsAlive(), ShouldBeFalse)
+			So(e3.HasComponent(ComponentC), ShouldBeTrue)
+		})
+
+		Convey("Dropping a mode stops its cleanup", func() {
+			systems.Cleanup()
+			context.SetComponentCleanup(ComponentA, CleanupNone)
+			e1.AddComponent(NewComponentA(3))
+			systems.Cleanup()
+			So(e1.HasComponent(ComponentA), ShouldBeTrue)
+			So(len(context.Groups()), ShouldEqual, 1)
+		})
+	})
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+const marker = "entitas:component"
+
+type config struct {
+	dir        string
+	output     string
+	entity     string
+	importPath string
+}
+
+type field struct {
+	Name  string
+	Param string
+	Type  string
+}
+
+type component struct {
+	Name    string
+	Index   int
+	Fields  []field
+	Cleanup string
+}
+
+// cleanupModes maps the cleanup option of the marker to the mode constant.
+var cleanupModes = map[string]string{
+	"remove":  "CleanupRemoveComponent",
+	"destroy": "CleanupDestroyEntity",
+}
+
+type importSpec struct {
+	Name string
+	Path string
+}
+
+type data struct {
+	Package       string
+	Entity        string
+	ImportPath    string
+	Imports       []importSpec
+	Components    []component
+	NumComponents int
+}
+
+// generate scans the package in cfg.dir and returns the formatted source of
+// the generated file. Components keep the indices the previous output
+// gave them; new components get the next free indices in name order, so
+// adding a component never renumbers the others.
+func generate(cfg config) ([]byte, error) {
+	files, err := filepath.Glob(filepath.Join(cfg.dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	d := data{
+		Entity:     cfg.entity,
+		ImportPath: cfg.importPath,
+	}
+	imports := make(map[string]importSpec)
+	fset := token.NewFileSet()
+
+	for _, name := range files {
+		base := filepath.Base(name)
+		if base == cfg.output || strings.HasSuffix(base, "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		d.Package = file.Name.Name
+
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+				options, ok := markerOptions(ts.Doc)
+				if !ok && len(gen.Specs) == 1 {
+					options, ok = markerOptions(gen.Doc)
+				}
+				if !ok {
+					continue
+				}
+
+				c, err := newComponent(fset, file, ts.Name.Name, st, imports)
+				if err != nil {
+					return nil, err
+				}
+				if err := c.setOptions(options); err != nil {
+					return nil, err
+				}
+				d.Components = append(d.Components, c)
+			}
+		}
+	}
+
+	if len(d.Components) == 0 {
+		return nil, fmt.Errorf("no structs marked //%s in %s", marker, cfg.dir)
+	}
+
+	indices, next, err := previousIndices(filepath.Join(cfg.dir, cfg.output))
+	if err != nil {
+		return nil, err
+	}
+	d.NumComponents = next
+	sort.Slice(d.Components, func(i, j int) bool {
+		return d.Components[i].Name < d.Components[j].Name
+	})
+	for i := range d.Components {
+		c := &d.Components[i]
+		if index, ok := indices[c.Name]; ok {
+			c.Index = index
+		} else {
+			c.Index = d.NumComponents
+			d.NumComponents++
+		}
+	}
+	sort.Slice(d.Components, func(i, j int) bool {
+		return d.Components[i].Index < d.Components[j].Index
+	})
+
+	for _, spec := range imports {
+		d.Imports = append(d.Imports, spec)
+	}
+	sort.Slice(d.Imports, func(i, j int) bool {
+		return d.Imports[i].Path < d.Imports[j].Path
+	})
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, d); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+// previousIndices reads the component indices and NumComponents from the
+// const block of a file generated before, written either with explicit
+// values or with iota. A missing file has no indices.
+func previousIndices(path string) (map[string]int, int, error) {
+	indices := make(map[string]int)
+	next := 0
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+	if errors.Is(err, fs.ErrNotExist) {
+		return indices, next, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		usesIota := false
+		for i, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			value, known := i, false
+			if len(vs.Values) > 0 {
+				usesIota = false
+				switch v := vs.Values[0].(type) {
+				case *ast.Ident:
+					usesIota = v.Name == "iota"
+				case *ast.BasicLit:
+					value, err = strconv.Atoi(v.Value)
+					known = err == nil
+				}
+			}
+			if !known && !usesIota {
+				continue
+			}
+			for _, n := range vs.Names {
+				if n.Name == "NumComponents" {
+					next = value
+				} else if name, ok := strings.CutPrefix(n.Name, "Component"); ok {
+					indices[name] = value
+				}
+			}
+		}
+	}
+	for _, index := range indices {
+		if index >= next {
+			next = index + 1
+		}
+	}
+	return indices, next, nil
+}
+
+// markerOptions reports whether doc holds the marker and returns the
+// options following it, such as cleanup=remove.
+func markerOptions(doc *ast.CommentGroup) ([]string, bool) {
+	if doc == nil {
+		return nil, false
+	}
+	for _, c := range doc.List {
+		text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+		if text == marker || strings.HasPrefix(text, marker+" ") {
+			return strings.Fields(strings.TrimPrefix(text, marker)), true
+		}
+	}
+	return nil, false
+}
+
+func (c *component) setOptions(options []string) error {
+	for _, option := range options {
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "cleanup":
+			mode, ok := cleanupModes[value]
+			if !ok {
+				return fmt.Errorf("%s: unknown cleanup mode %q", c.Name, value)
+			}
+			c.Cleanup = mode
+		default:
+			return fmt.Errorf("%s: unknown option %q", c.Name, option)
+		}
+	}
+	return nil
+}
+
+func newComponent(fset *token.FileSet, file *ast.File, name string, st *ast.StructType, imports map[string]importSpec) (component, error) {
+	c := component{Name: name}
+	for _, f := range st.Fields.List {
+		var typ bytes.Buffer
+		if err := format.Node(&typ, fset, f.Type); err != nil {
+			return c, err
+		}
+		if err := addImports(file, f.Type, imports); err != nil {
+			return c, err
+		}
+
+		for _, n := range f.Names {
+			if !n.IsExported() {
+				continue
+			}
+			c.Fields = append(c.Fields, field{
+				Name:  n.Name,
+				Param: "new" + n.Name,
+				Type:  typ.String(),
+			})
+		}
+	}
+	return c, nil
+}
+
+// addImports records the imports of file that expr refers to.
+func addImports(file *ast.File, expr ast.Expr, imports map[string]importSpec) error {
+	var err error
+	ast.Inspect(expr, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		pkg, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+
+		for _, spec := range file.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			if spec.Name != nil {
+				if spec.Name.Name == pkg.Name {
+					imports[path] = importSpec{Name: pkg.Name, Path: path}
+					return false
+				}
+			} else if packageName(path) == pkg.Name {
+				imports[path] = importSpec{Path: path}
+				return false
+			}
+		}
+		err = fmt.Errorf("%s: unknown package %s", file.Name.Name, pkg.Name)
+		return false
+	})
+	return err
+}
+
+// packageName guesses the name of the package at path from its last
+// element, ignoring a major version suffix and a go- prefix.
+func packageName(path string) string {
+	parts := strings.Split(path, "/")
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && len(name) > 1 && name[0] == 'v' && unicode.IsDigit(rune(name[1])) {
+		name = parts[len(parts)-2]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' {
+			return -1
+		}
+		return r
+	}, name)
+}
+
+var tmpl = template.Must(template.New("entitas").Parse(`// Code generated by entitas-gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	entitas "{{.ImportPath}}"
+{{- range .Imports}}
+	{{if .Name}}{{.Name}} {{end}}"{{.Path}}"
+{{- end}}
+)
+
+const (
+{{- range .Components}}
+	Component{{.Name}} = {{.Index}}
+{{- end}}
+	NumComponents = {{.NumComponents}}
+)
+
+var (
+{{- range .Components}}
+	Matcher{{.Name}} = entitas.AllOf(Component{{.Name}})
+{{- end}}
+)
+
+{{range .Components}}
+func (c *{{.Name}}) Type() int {
+	return Component{{.Name}}
+}
+{{end}}
+
+// RegisterComponents registers every generated component with context.
+func RegisterComponents(context entitas.Context) {
+{{- range .Components}}
+	context.RegisterComponent(&{{.Name}}{})
+{{- end}}
+{{- range .Components}}{{if .Cleanup}}
+	context.SetComponentCleanup(Component{{.Name}}, entitas.{{.Cleanup}})
+{{- end}}{{end}}
+}
+
+// {{.Entity}} adds typed component accessors to an entitas.Entity.
+type {{.Entity}} struct {
+	entitas.Entity
+}
+{{$entity := .Entity}}
+{{range .Components}}
+func (e {{$entity}}) Has{{.Name}}() bool {
+	return e.HasComponent(Component{{.Name}})
+}
+
+func (e {{$entity}}) Get{{.Name}}() *{{.Name}} {
+	c, _ := e.Component(Component{{.Name}})
+	component, _ := c.(*{{.Name}})
+	return component
+}
+
+func (e {{$entity}}) Add{{.Name}}({{range $i, $f := .Fields}}{{if $i}}, {{end}}{{$f.Param}} {{$f.Type}}{{end}}) error {
+	if !e.IsAlive() {
+		return entitas.ErrEntityDestroyed
+	}
+	if e.Has{{.Name}}() {
+		return entitas.ErrComponentExists
+	}
+	c := e.CreateComponent(Component{{.Name}}).(*{{.Name}})
+	*c = {{.Name}}{ {{- range $i, $f := .Fields}}{{if $i}}, {{end}}{{$f.Name}}: {{$f.Param}}{{end -}} }
+	return e.AddComponent(c)
+}
+
+func (e {{$entity}}) Replace{{.Name}}({{range $i, $f := .Fields}}{{if $i}}, {{end}}{{$f.Param}} {{$f.Type}}{{end}}) error {
+	if !e.IsAlive() {
+		return entitas.ErrEntityDestroyed
+	}
+	c := e.CreateComponent(Component{{.Name}}).(*{{.Name}})
+	*c = {{.Name}}{ {{- range $i, $f := .Fields}}{{if $i}}, {{end}}{{$f.Name}}: {{$f.Param}}{{end -}} }
+	return e.UpdateComponent(c)
+}
+
+func (e {{$entity}}) Remove{{.Name}}() error {
+	return e.RemoveComponent(Component{{.Name}})
+}
+{{end}}
+`))
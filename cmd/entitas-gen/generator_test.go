@@ -0,0 +1,185 @@
+package main
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// usageTest is compiled and run against the generated code of testdata.
+const usageTest = `package game
+
+import (
+	"testing"
+
+	entitas "github.com/jangsky215/go-entitas"
+)
+
+func TestGenerated(t *testing.T) {
+	context := entitas.NewContext(0, NumComponents)
+	RegisterComponents(context)
+	e := Entity{context.CreateEntity()}
+
+	if err := e.AddPosition(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	e.GetPosition().dirty = true
+	e.RemoveComponent(ComponentPosition)
+	if err := e.AddPosition(3, 4); err != nil {
+		t.Fatal(err)
+	}
+	if p := e.GetPosition(); *p != (Position{X: 3, Y: 4}) {
+		t.Fatalf("position is %+v", *p)
+	}
+	if err := e.ReplacePosition(5, 6); err != nil {
+		t.Fatal(err)
+	}
+	if !e.HasPosition() || e.GetPosition().X != 5 {
+		t.Fatal("position not replaced")
+	}
+
+	pooled := entitas.NewContext(0, NumComponents, entitas.WithComponentPool(ComponentPosition, 1, 0))
+	RegisterComponents(pooled)
+	e = Entity{pooled.CreateEntity()}
+	e.AddPosition(1, 2)
+	if err := e.AddPosition(3, 4); err != entitas.ErrComponentExists {
+		t.Fatalf("adding twice returned %v", err)
+	}
+	e.Destroy()
+	if err := e.ReplacePosition(5, 6); err != entitas.ErrEntityDestroyed {
+		t.Fatalf("replacing on a destroyed entity returned %v", err)
+	}
+	if stats := pooled.ComponentPoolStats(ComponentPosition); stats.Hits+stats.Misses != 1 || stats.Size != 1 {
+		t.Fatalf("components were created for failed calls: %+v", stats)
+	}
+}
+`
+
+func TestGenerate(t *testing.T) {
+	Convey("Given a package with marked components", t, func() {
+		src, err := generate(config{
+			dir:        "testdata",
+			output:     "entitas_gen.go",
+			entity:     "Entity",
+			importPath: "github.com/jangsky215/go-entitas",
+		})
+		So(err, ShouldBeNil)
+		code := string(src)
+
+		Convey("Components get indices in name order", func() {
+			So(code, ShouldContainSubstring, "ComponentDestroyed = 0\n\tComponentPosition  = 1\n\tComponentTimer     = 2\n\tComponentVelocity  = 3\n\tNumComponents      = 4")
+			So(code, ShouldNotContainSubstring, "NotAComponent")
+		})
+
+		Convey("Type methods, matchers and registration are generated", func() {
+			So(code, ShouldContainSubstring, "func (c *Position) Type() int {\n\treturn ComponentPosition\n}")
+			So(code, ShouldContainSubstring, "MatcherPosition  = entitas.AllOf(ComponentPosition)")
+			So(code, ShouldContainSubstring, "context.RegisterComponent(&Position{})")
+			So(code, ShouldContainSubstring, "context.SetComponentCleanup(ComponentDestroyed, entitas.CleanupDestroyEntity)")
+			So(code, ShouldNotContainSubstring, "SetComponentCleanup(ComponentPosition")
+		})
+
+		Convey("Typed accessors take the exported fields", func() {
+			So(code, ShouldContainSubstring, "func (e Entity) HasPosition() bool")
+			So(code, ShouldContainSubstring, "func (e Entity) GetPosition() *Position")
+			So(code, ShouldContainSubstring, "func (e Entity) AddPosition(newX int, newY int) error")
+			So(code, ShouldContainSubstring, "func (e Entity) ReplaceTimer(newLeft time.Duration) error")
+			So(code, ShouldContainSubstring, "func (e Entity) AddDestroyed() error")
+			So(code, ShouldContainSubstring, "*c = Position{X: newX, Y: newY}")
+			So(code, ShouldContainSubstring, "*c = Destroyed{}")
+			So(code, ShouldContainSubstring, "\t\"time\"\n")
+		})
+	})
+
+	Convey("Given a package generated before", t, func() {
+		dir := t.TempDir()
+		copyFile(t, "testdata/components.go", filepath.Join(dir, "components.go"))
+		previous := "package game\n\nconst (\n\tComponentVelocity = iota\n\tComponentTimer\n\tNumComponents\n)\n"
+		So(os.WriteFile(filepath.Join(dir, "entitas_gen.go"), []byte(previous), 0o644), ShouldBeNil)
+
+		src, err := generate(config{
+			dir:        dir,
+			output:     "entitas_gen.go",
+			entity:     "Entity",
+			importPath: "github.com/jangsky215/go-entitas",
+		})
+		So(err, ShouldBeNil)
+
+		Convey("Existing components keep their indices and new ones follow", func() {
+			So(string(src), ShouldContainSubstring, "ComponentVelocity  = 0\n\tComponentTimer     = 1\n\tComponentDestroyed = 2\n\tComponentPosition  = 3\n\tNumComponents      = 4")
+		})
+
+		Convey("Generating again gives the same indices", func() {
+			So(os.WriteFile(filepath.Join(dir, "entitas_gen.go"), src, 0o644), ShouldBeNil)
+			again, err := generate(config{
+				dir:        dir,
+				output:     "entitas_gen.go",
+				entity:     "Entity",
+				importPath: "github.com/jangsky215/go-entitas",
+			})
+			So(err, ShouldBeNil)
+			So(string(again), ShouldEqual, string(src))
+		})
+
+		Convey("A removed component leaves its index unused", func() {
+			previous := "package game\n\nconst (\n\tComponentVelocity = 0\n\tNumComponents = 5\n)\n"
+			So(os.WriteFile(filepath.Join(dir, "entitas_gen.go"), []byte(previous), 0o644), ShouldBeNil)
+			src, err := generate(config{
+				dir:        dir,
+				output:     "entitas_gen.go",
+				entity:     "Entity",
+				importPath: "github.com/jangsky215/go-entitas",
+			})
+			So(err, ShouldBeNil)
+			So(string(src), ShouldContainSubstring, "ComponentVelocity  = 0\n\tComponentDestroyed = 5\n\tComponentPosition  = 6\n\tComponentTimer     = 7\n\tNumComponents      = 8")
+		})
+	})
+}
+
+func TestGeneratedCodeCompiles(t *testing.T) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	// The package lives under testdata so it builds inside this module.
+	dir, err := os.MkdirTemp("testdata", "compile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	copyFile(t, "testdata/components.go", filepath.Join(dir, "components.go"))
+	src, err := generate(config{
+		dir:        dir,
+		output:     "entitas_gen.go",
+		entity:     "Entity",
+		importPath: "github.com/jangsky215/go-entitas",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "entitas_gen.go"), src, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "usage_test.go"), []byte(usageTest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command(goTool, "test", "./"+filepath.ToSlash(dir)).CombinedOutput()
+	if err != nil {
+		t.Fatalf("generated code does not work: %v\n%s", err, strings.TrimSpace(string(out)))
+	}
+}
+
+func copyFile(t *testing.T, from, to string) {
+	src, err := os.ReadFile(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(to, src, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
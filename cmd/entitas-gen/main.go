@@ -0,0 +1,45 @@
+// Command entitas-gen generates component type indices, matchers and typed
+// entity accessors for the structs of a package marked with
+//
+//	//entitas:component
+//
+// A marker may register the component for the cleanup system, which
+// removes it or destroys its entity at the end of every frame:
+//
+//	//entitas:component cleanup=remove
+//	//entitas:component cleanup=destroy
+//
+// Indices are read back from the previous output, so adding a component
+// never renumbers the others. Removing one leaves a gap.
+//
+// It is meant to run from go:generate:
+//
+//	//go:generate entitas-gen
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	var cfg config
+	flag.StringVar(&cfg.dir, "dir", ".", "package directory to scan")
+	flag.StringVar(&cfg.output, "output", "entitas_gen.go", "file to write, relative to dir")
+	flag.StringVar(&cfg.entity, "entity", "Entity", "name of the generated entity wrapper type")
+	flag.StringVar(&cfg.importPath, "import", "github.com/jangsky215/go-entitas", "import path of the entitas package")
+	flag.Parse()
+
+	src, err := generate(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "entitas-gen:", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(filepath.Join(cfg.dir, cfg.output), src, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "entitas-gen:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,30 @@
+package game
+
+import (
+	"time"
+)
+
+//entitas:component
+type Velocity struct {
+	X, Y float64
+}
+
+// Position is where an entity is.
+//
+//entitas:component
+type Position struct {
+	X, Y  int
+	dirty bool
+}
+
+//entitas:component
+type Timer struct {
+	Left time.Duration
+}
+
+//entitas:component cleanup=destroy
+type Destroyed struct{}
+
+type NotAComponent struct {
+	Value int
+}
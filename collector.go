@@ -0,0 +1,229 @@
+package entitas
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Trigger names a group and the group event a Collector listens to.
+type Trigger struct {
+	Group Group
+	Event EventType
+}
+
+// Collector gathers the entities its triggers fired for, in the order they
+// were first collected. Collected entities are retained until Clear, so a
+// destroyed entity stays safe to read and its ID is not reused meanwhile.
+type Collector interface {
+	GroupObserver
+	Count() int
+	Clear()
+}
+
+// CollectedEvent is one group event a HistoryCollector recorded for an
+// entity. Seq grows with every event the collector records, so it orders
+// events across entities. Component is a shallow copy of the component
+// that caused the event, taken when it fired, since the component itself
+// may be pooled and handed out again by CreateComponent.
+type CollectedEvent struct {
+	Seq       uint64
+	Group     Group
+	Event     EventType
+	Component Component
+}
+
+type CollectorOrder uint
+
+const (
+	CollectorOrderFirstEvent CollectorOrder = iota
+	CollectorOrderLastEvent
+)
+
+// HistoryCollector is a Collector that also keeps, for every collected
+// entity, the events it was collected for.
+type HistoryCollector interface {
+	Collector
+	History(e Entity) []CollectedEvent
+	SortedEntities(order CollectorOrder) []Entity
+}
+
+type collector struct {
+	triggers      []Trigger
+	entities      []Entity
+	index         map[Entity]bool
+	subscriptions []*subscription
+	lock          *contextLock
+
+	history map[Entity][]CollectedEvent
+	seq     uint64
+}
+
+// NewCollector collects for every trigger. All groups must come from the
+// same context.
+func NewCollector(triggers ...Trigger) Collector {
+	return newCollector(triggers, false)
+}
+
+// NewHistoryCollector is NewCollector, recording every event as well.
+func NewHistoryCollector(triggers ...Trigger) HistoryCollector {
+	return newCollector(triggers, true)
+}
+
+func newCollector(triggers []Trigger, history bool) *collector {
+	if len(triggers) == 0 {
+		panic("collector without triggers")
+	}
+	c := &collector{
+		triggers: triggers,
+		index:    make(map[Entity]bool),
+		lock:     triggers[0].Group.mutex(),
+	}
+	if history {
+		c.history = make(map[Entity][]CollectedEvent)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.subscribe()
+	return c
+}
+
+func (c *collector) CollectedEntities() []Entity {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return append([]Entity(nil), c.entities...)
+}
+
+func (c *collector) Count() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return len(c.entities)
+}
+
+func (c *collector) Activate() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.subscriptions == nil {
+		c.subscribe()
+	}
+}
+
+// Deactivate detaches the collector from its groups. Entities collected so
+// far are kept until Clear.
+func (c *collector) Deactivate() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, s := range c.subscriptions {
+		s.cancel()
+	}
+	c.subscriptions = nil
+}
+
+func (c *collector) Clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, e := range c.entities {
+		e.release(c)
+	}
+	c.lock.beginWrite()
+	defer c.lock.endWrite()
+
+	c.entities = nil
+	c.index = make(map[Entity]bool)
+	if c.history != nil {
+		c.history = make(map[Entity][]CollectedEvent)
+	}
+}
+
+func (c *collector) ClearCollectedEntities() {
+	c.Clear()
+}
+
+// History returns the events e was collected for, oldest first.
+func (c *collector) History(e Entity) []CollectedEvent {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return append([]CollectedEvent(nil), c.history[e]...)
+}
+
+// SortedEntities returns the collected entities ordered by the first or
+// the last event recorded for them.
+func (c *collector) SortedEntities(order CollectorOrder) []Entity {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	entities := append([]Entity(nil), c.entities...)
+	if order == CollectorOrderLastEvent {
+		sort.SliceStable(entities, func(i, j int) bool {
+			return c.lastSeq(entities[i]) < c.lastSeq(entities[j])
+		})
+	}
+	return entities
+}
+
+// private
+func (c *collector) subscribe() {
+	for _, t := range c.triggers {
+		if t.Event == EventAddedOrRemoved {
+			c.subscriptions = append(c.subscriptions,
+				c.listen(t.Group, EventAdded),
+				c.listen(t.Group, EventRemoved))
+		} else {
+			c.subscriptions = append(c.subscriptions, c.listen(t.Group, t.Event))
+		}
+	}
+}
+
+func (c *collector) listen(g Group, ev EventType) *subscription {
+	return g.listen(ev, func(g Group, e Entity, comp Component) {
+		c.collect(g, ev, e, comp)
+	})
+}
+
+func (c *collector) collect(g Group, ev EventType, e Entity, comp Component) {
+	if !c.index[e] {
+		e.retain(c)
+		c.lock.beginWrite()
+		c.index[e] = true
+		c.entities = append(c.entities, e)
+		c.lock.endWrite()
+	}
+	if c.history != nil {
+		copied := copyComponent(comp)
+		c.lock.beginWrite()
+		c.seq++
+		c.history[e] = append(c.history[e], CollectedEvent{
+			Seq:       c.seq,
+			Group:     g,
+			Event:     ev,
+			Component: copied,
+		})
+		c.lock.endWrite()
+	}
+}
+
+// copyComponent returns a shallow copy of a component held by pointer, and
+// any other component as it is.
+func copyComponent(c Component) Component {
+	v := reflect.ValueOf(c)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return c
+	}
+	copied := reflect.New(v.Elem().Type())
+	copied.Elem().Set(v.Elem())
+	return copied.Interface().(Component)
+}
+
+func (c *collector) lastSeq(e Entity) uint64 {
+	events := c.history[e]
+	if len(events) == 0 {
+		return 0
+	}
+	return events[len(events)-1].Seq
+}
@@ -0,0 +1,99 @@
+package entitas
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestCollector(t *testing.T) {
+	Convey("Given a collector on added to A and removed from B", t, func() {
+		context := NewContext(0, NumComponents)
+		collector := NewCollector(
+			Trigger{Group: context.Group(AllOf(ComponentA)), Event: EventAdded},
+			Trigger{Group: context.Group(AllOf(ComponentB)), Event: EventRemoved},
+		)
+		e1 := context.CreateEntity(NewComponentB(0))
+		e2 := context.CreateEntity(NewComponentA(1))
+
+		Convey("It collects in insertion order without duplicates", func() {
+			e1.RemoveComponent(ComponentB)
+			e2.AddComponent(NewComponentB(0))
+			e2.RemoveComponent(ComponentB)
+			So(collector.CollectedEntities(), ShouldResemble, []Entity{e2, e1})
+			So(collector.Count(), ShouldEqual, 2)
+		})
+
+		Convey("It retains destroyed entities until cleared", func() {
+			e2.Destroy()
+			So(e2.RetainCount(), ShouldEqual, 1)
+			So(context.ReusableCount(), ShouldEqual, 0)
+
+			collector.Clear()
+			So(collector.Count(), ShouldEqual, 0)
+			So(context.ReusableCount(), ShouldEqual, 1)
+		})
+
+		Convey("A deactivated collector keeps its entities but stops collecting", func() {
+			collector.Deactivate()
+			context.CreateEntity(NewComponentA(2))
+			e1.RemoveComponent(ComponentB)
+			So(collector.CollectedEntities(), ShouldResemble, []Entity{e2})
+
+			collector.Activate()
+			e3 := context.CreateEntity(NewComponentA(3))
+			So(collector.CollectedEntities(), ShouldResemble, []Entity{e2, e3})
+		})
+	})
+}
+
+func TestHistoryCollector(t *testing.T) {
+	Convey("Given a history collector on every event of group A", t, func() {
+		context := NewContext(0, NumComponents)
+		group := context.Group(AllOf(ComponentA))
+		collector := NewHistoryCollector(
+			Trigger{Group: group, Event: EventAddedOrRemoved},
+			Trigger{Group: group, Event: EventUpdated},
+		)
+
+		a1 := NewComponentA(1)
+		e1 := context.CreateEntity(a1)
+		e2 := context.CreateEntity(NewComponentA(2))
+		a3 := NewComponentA(3)
+		e1.UpdateComponent(a3)
+
+		Convey("It records the events and their components in order", func() {
+			history := collector.History(e1)
+			So(len(history), ShouldEqual, 2)
+			So(history[0].Seq, ShouldEqual, 1)
+			So(history[0].Group, ShouldEqual, group)
+			So(history[0].Event, ShouldEqual, EventAdded)
+			So(history[0].Component, ShouldResemble, a1)
+			So(history[1].Seq, ShouldEqual, 3)
+			So(history[1].Event, ShouldEqual, EventUpdated)
+			So(history[1].Component, ShouldResemble, a3)
+			So(collector.History(e2)[0].Event, ShouldEqual, EventAdded)
+		})
+
+		Convey("It keeps the values after the components are pooled and reused", func() {
+			context.RegisterComponent(&componentA{})
+			e1.RemoveComponent(ComponentA)
+			reused := context.CreateComponent(ComponentA).(*componentA)
+			So(reused, ShouldEqual, a3)
+			reused.value = 99
+
+			history := collector.History(e1)
+			So(history[1].Component, ShouldResemble, NewComponentA(3))
+			So(history[2].Component, ShouldResemble, NewComponentA(3))
+		})
+
+		Convey("It sorts by first or last event", func() {
+			So(collector.SortedEntities(CollectorOrderFirstEvent), ShouldResemble, []Entity{e1, e2})
+			So(collector.SortedEntities(CollectorOrderLastEvent), ShouldResemble, []Entity{e2, e1})
+		})
+
+		Convey("Clear forgets the history", func() {
+			collector.Clear()
+			So(collector.History(e1), ShouldBeEmpty)
+		})
+	})
+}
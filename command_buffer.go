@@ -0,0 +1,154 @@
+package entitas
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrForeignEntityRef = errors.New("entity reference does not belong to the command buffer")
+)
+
+// EntityRef names the target of a command: an entity that existed when the
+// command was recorded, or one created earlier by the same CommandBuffer.
+type EntityRef struct {
+	handle  EntityHandle
+	buffer  *CommandBuffer
+	created int
+}
+
+// Ref refers to the existing entity e. If e is destroyed before the buffer
+// is played back, commands on it fail with ErrEntityDestroyed.
+func Ref(e Entity) EntityRef {
+	return EntityRef{handle: e.Handle()}
+}
+
+type commandKind uint
+
+const (
+	commandCreate commandKind = iota
+	commandDestroy
+	commandAdd
+	commandReplace
+	commandRemove
+)
+
+type command struct {
+	kind       commandKind
+	ref        EntityRef
+	components []Component
+	types      []int
+}
+
+// CommandBuffer records structural changes and plays them back on a
+// context at a sync point, so they can be requested while iterating over
+// groups or from workers running in parallel. A CommandBuffer is not safe
+// for concurrent use; give every worker its own and merge them afterwards.
+type CommandBuffer struct {
+	commands []command
+	created  int
+}
+
+func NewCommandBuffer() *CommandBuffer {
+	return &CommandBuffer{}
+}
+
+// CreateEntity records the creation of an entity with cs and returns a
+// reference later commands of the buffer can use.
+func (b *CommandBuffer) CreateEntity(cs ...Component) EntityRef {
+	b.created++
+	ref := EntityRef{buffer: b, created: b.created}
+	b.commands = append(b.commands, command{kind: commandCreate, ref: ref, components: cs})
+	return ref
+}
+
+func (b *CommandBuffer) DestroyEntity(ref EntityRef) {
+	b.commands = append(b.commands, command{kind: commandDestroy, ref: ref})
+}
+
+func (b *CommandBuffer) AddComponent(ref EntityRef, cs ...Component) {
+	b.commands = append(b.commands, command{kind: commandAdd, ref: ref, components: cs})
+}
+
+func (b *CommandBuffer) ReplaceComponent(ref EntityRef, cs ...Component) {
+	b.commands = append(b.commands, command{kind: commandReplace, ref: ref, components: cs})
+}
+
+func (b *CommandBuffer) RemoveComponent(ref EntityRef, ts ...int) {
+	b.commands = append(b.commands, command{kind: commandRemove, ref: ref, types: ts})
+}
+
+func (b *CommandBuffer) Len() int {
+	return len(b.commands)
+}
+
+func (b *CommandBuffer) Reset() {
+	b.commands = b.commands[:0]
+	b.created = 0
+}
+
+// Merge appends the commands of others to b, one buffer after the other in
+// the order given, so merging the same buffers always gives the same
+// result. Entities created by others get new references in b; the
+// references others returned keep pointing into others, so commands
+// recorded on b with them fail with ErrForeignEntityRef.
+func (b *CommandBuffer) Merge(others ...*CommandBuffer) {
+	for _, other := range others {
+		offset := b.created
+		for _, c := range other.commands {
+			if c.ref.buffer == other {
+				c.ref.buffer = b
+				c.ref.created += offset
+			}
+			b.commands = append(b.commands, c)
+		}
+		b.created += other.created
+	}
+}
+
+// Playback applies the recorded commands to context in order and resets
+// the buffer. A command that fails, for example because another system
+// destroyed its entity, is skipped and the rest still apply; the errors of
+// all skipped commands are returned joined.
+func (b *CommandBuffer) Playback(context Context) error {
+	defer b.Reset()
+
+	var errs []error
+	created := make([]Entity, b.created+1)
+	for i, c := range b.commands {
+		if c.kind == commandCreate {
+			created[c.ref.created] = context.CreateEntity(c.components...)
+			continue
+		}
+
+		var e Entity
+		if c.ref.buffer != nil {
+			if c.ref.buffer != b || c.ref.created >= len(created) || created[c.ref.created] == nil {
+				errs = append(errs, fmt.Errorf("command %d: %w", i, ErrForeignEntityRef))
+				continue
+			}
+			e = created[c.ref.created]
+		} else if resolved, ok := context.Resolve(c.ref.handle); ok {
+			e = resolved
+		} else {
+			errs = append(errs, fmt.Errorf("command %d: %w", i, ErrEntityDestroyed))
+			continue
+		}
+
+		var err error
+		switch c.kind {
+		case commandDestroy:
+			err = e.Destroy()
+		case commandAdd:
+			err = e.AddComponent(c.components...)
+		case commandReplace:
+			err = e.UpdateComponent(c.components...)
+		case commandRemove:
+			err = e.RemoveComponent(c.types...)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("command %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,90 @@
+package entitas
+
+import (
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestCommandBuffer(t *testing.T) {
+	Convey("Given a context and a command buffer", t, func() {
+		context := NewContext(0, NumComponents)
+		group := context.Group(AllOf(ComponentA))
+		e1 := context.CreateEntity(NewComponentA(1))
+		e2 := context.CreateEntity(NewComponentA(2))
+		buffer := NewCommandBuffer()
+
+		Convey("Changes recorded while iterating a group apply at playback", func() {
+			for _, e := range group.Entities() {
+				buffer.DestroyEntity(Ref(e))
+			}
+			So(group.Count(), ShouldEqual, 2)
+
+			So(buffer.Playback(context), ShouldBeNil)
+			So(group.Count(), ShouldEqual, 0)
+			So(buffer.Len(), ShouldEqual, 0)
+		})
+
+		Convey("Entities created by the buffer can be changed by it", func() {
+			ref := buffer.CreateEntity(NewComponentB(1))
+			buffer.AddComponent(ref, NewComponentA(3))
+			buffer.ReplaceComponent(ref, NewComponentB(2))
+			buffer.RemoveComponent(Ref(e1), ComponentA)
+
+			So(buffer.Playback(context), ShouldBeNil)
+			So(context.Count(), ShouldEqual, 3)
+			So(group.Count(), ShouldEqual, 2)
+			So(e1.HasComponent(ComponentA), ShouldBeFalse)
+		})
+
+		Convey("Failed commands are skipped and reported", func() {
+			buffer.AddComponent(Ref(e2), NewComponentB(1))
+			buffer.AddComponent(Ref(e1), NewComponentA(1))
+			buffer.AddComponent(Ref(e1), NewComponentB(1))
+			e2.Destroy()
+			context.CreateEntity()
+
+			err := buffer.Playback(context)
+			So(errors.Is(err, ErrEntityDestroyed), ShouldBeTrue)
+			So(errors.Is(err, ErrComponentExists), ShouldBeTrue)
+			So(e1.HasComponent(ComponentB), ShouldBeTrue)
+			So(buffer.Len(), ShouldEqual, 0)
+		})
+
+		Convey("Buffers of several workers merge in order", func() {
+			workers := []*CommandBuffer{NewCommandBuffer(), NewCommandBuffer()}
+			a := workers[0].CreateEntity()
+			workers[0].AddComponent(a, NewComponentA(10))
+			b := workers[1].CreateEntity()
+			workers[1].AddComponent(b, NewComponentB(20))
+			workers[1].DestroyEntity(Ref(e1))
+
+			buffer.Merge(workers...)
+			So(buffer.Len(), ShouldEqual, 5)
+			So(buffer.Playback(context), ShouldBeNil)
+
+			So(context.Count(), ShouldEqual, 3)
+			first, _ := context.Resolve(EntityHandle{ID: 2})
+			So(first.HasComponent(ComponentA), ShouldBeTrue)
+			second, _ := context.Resolve(EntityHandle{ID: 3})
+			So(second.HasComponent(ComponentB), ShouldBeTrue)
+		})
+
+		Convey("References of other buffers are reported", func() {
+			other := NewCommandBuffer()
+			ref := other.CreateEntity()
+			buffer.AddComponent(ref, NewComponentA(1))
+			So(errors.Is(buffer.Playback(context), ErrForeignEntityRef), ShouldBeTrue)
+
+			buffer.Merge(other)
+			buffer.AddComponent(ref, NewComponentA(1))
+			So(errors.Is(buffer.Playback(context), ErrForeignEntityRef), ShouldBeTrue)
+			So(context.Count(), ShouldEqual, 3)
+
+			stale := buffer.CreateEntity()
+			buffer.Reset()
+			buffer.DestroyEntity(stale)
+			So(errors.Is(buffer.Playback(context), ErrForeignEntityRef), ShouldBeTrue)
+		})
+	})
+}
@@ -3,3 +3,48 @@ package entitas
 type Component interface {
 	Type() int
 }
+
+// A component may implement the hooks below to follow its own lifecycle.
+// The entity calls them once its storage has changed and before it fires
+// the matching events, so groups, observers and the component pool all
+// see the change only after the hook returned.
+//
+// In a context created WithConcurrency hooks are queued like event
+// listeners and run once the call released the lock. Groups, observers
+// and indices have then already seen the change; listeners added with
+// AddEvent still run after the hook, and a removed component is still
+// pooled only after its hooks ran.
+
+// ComponentAddedHook is called when the component is attached to e,
+// including when UpdateComponent swaps it in for another component. Every
+// OnAdded is paired with one OnRemoved.
+type ComponentAddedHook interface {
+	OnAdded(e Entity)
+}
+
+// ComponentRemovedHook is called when the component is detached from e,
+// including when UpdateComponent swaps it for another component.
+type ComponentRemovedHook interface {
+	OnRemoved(e Entity)
+}
+
+// ComponentReplacedHook is called on the component UpdateComponent stores
+// in place of old, after its OnAdded if it is a new instance. old is the
+// component itself when it was updated in place.
+type ComponentReplacedHook interface {
+	OnReplaced(e Entity, old Component)
+}
+
+type Components []Component
+
+func (cs Components) Len() int {
+	return len(cs)
+}
+
+func (cs Components) Less(i, j int) bool {
+	return cs[i].Type() < cs[j].Type()
+}
+
+func (cs Components) Swap(i, j int) {
+	cs[i], cs[j] = cs[j], cs[i]
+}
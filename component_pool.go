@@ -0,0 +1,95 @@
+package entitas
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Resettable is implemented by components that clear their fields when
+// they return to the component pool, so CreateComponent never hands out
+// state left over from a removed component.
+type Resettable interface {
+	Reset()
+}
+
+// ComponentPoolStats describes the pool of one component type. Hits and
+// Misses count CreateComponent calls served from the pool and by a new
+// allocation; Size is the number of pooled components.
+type ComponentPoolStats struct {
+	Hits   int
+	Misses int
+	Size   int
+}
+
+// WithComponentPool sets the pool policy of component type t. At most
+// capacity removed components are kept for reuse; 0 disables pooling and
+// a negative capacity, the default, keeps them all. preallocate components
+// are created as soon as the type is registered.
+func WithComponentPool(t int, capacity, preallocate int) ContextOption {
+	return func(p *context) {
+		if t < 0 || t >= p.totalComponents {
+			panic(fmt.Sprintf("component type %d out of range [0, %d)", t, p.totalComponents))
+		}
+		p.pools[t].capacity = capacity
+		p.pools[t].preallocate = preallocate
+	}
+}
+
+type componentPool struct {
+	components  []Component
+	capacity    int
+	preallocate int
+	hits        int
+	misses      int
+}
+
+func newComponentPools(totalComponents int) []componentPool {
+	pools := make([]componentPool, totalComponents)
+	for i := range pools {
+		pools[i].capacity = -1
+	}
+	return pools
+}
+
+func (pool *componentPool) get(t reflect.Type) Component {
+	length := len(pool.components)
+	if length == 0 {
+		pool.misses++
+		return reflect.New(t).Interface().(Component)
+	}
+	pool.hits++
+	last := length - 1
+	c := pool.components[last]
+	pool.components[last] = nil
+	pool.components = pool.components[:last]
+	return c
+}
+
+func (pool *componentPool) put(c Component) {
+	if pool.capacity >= 0 && len(pool.components) >= pool.capacity {
+		return
+	}
+	if r, ok := c.(Resettable); ok {
+		r.Reset()
+	}
+	pool.components = append(pool.components, c)
+}
+
+// fill creates the preallocated components once t is known.
+func (pool *componentPool) fill(t reflect.Type) {
+	n := pool.preallocate
+	if pool.capacity >= 0 && n > pool.capacity {
+		n = pool.capacity
+	}
+	for len(pool.components) < n {
+		pool.components = append(pool.components, reflect.New(t).Interface().(Component))
+	}
+}
+
+func (pool *componentPool) stats() ComponentPoolStats {
+	return ComponentPoolStats{
+		Hits:   pool.hits,
+		Misses: pool.misses,
+		Size:   len(pool.components),
+	}
+}
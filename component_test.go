@@ -26,7 +26,7 @@ func NewComponentA(value int) Component {
 	return &componentA{value}
 }
 
-func (c1 *componentA) int() int {
+func (c1 *componentA) Type() int {
 	return ComponentA
 }
 
@@ -44,7 +44,7 @@ func NewComponentB(value float32) Component {
 	return &componentB{value}
 }
 
-func (c1 *componentB) int() int {
+func (c1 *componentB) Type() int {
 	return ComponentB
 }
 
@@ -61,7 +61,7 @@ func NewComponentC() Component {
 	return &componentC{}
 }
 
-func (c1 *componentC) int() int {
+func (c1 *componentC) Type() int {
 	return ComponentC
 }
 
@@ -75,10 +75,10 @@ type componentD struct {
 }
 
 func NewComponentD() Component {
-	return &componentC{}
+	return &componentD{}
 }
 
-func (c1 *componentD) int() int {
+func (c1 *componentD) Type() int {
 	return ComponentD
 }
 
@@ -95,7 +95,7 @@ func NewComponentE() Component {
 	return &componentE{}
 }
 
-func (c1 *componentE) int() int {
+func (c1 *componentE) Type() int {
 	return ComponentE
 }
 
@@ -112,7 +112,7 @@ func NewComponentF() Component {
 	return &componentF{}
 }
 
-func (c1 *componentF) int() int {
+func (c1 *componentF) Type() int {
 	return ComponentF
 }
 
@@ -0,0 +1,144 @@
+package entitas
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrency(t *testing.T) {
+	Convey("Given a concurrent context used by many goroutines", t, func() {
+		context := NewContext(0, NumComponents, WithConcurrency())
+		group := context.Group(AllOf(ComponentA))
+		observer := NewGroupObserver(group, EventAddedOrRemoved)
+		index := NewEntityIndex(context, AllOf(ComponentB), func(e Entity) interface{} {
+			c, _ := e.Component(ComponentB)
+			return c.(*componentB).value
+		})
+
+		const workers, rounds = 8, 200
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < rounds; i++ {
+					e := context.CreateEntity(NewComponentA(i))
+					e.AddComponent(NewComponentB(float32(w)))
+					e.UpdateComponent(NewComponentA(i + 1))
+					e.HasComponent(ComponentA, ComponentB)
+					for _, other := range group.Entities() {
+						other.Component(ComponentA)
+					}
+					index.Entities(float32(w))
+					observer.CollectedEntities()
+					if i%2 == 0 {
+						e.RemoveComponent(ComponentA)
+					} else {
+						e.Destroy()
+					}
+					if i%50 == 0 {
+						observer.ClearCollectedEntities()
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+
+		Convey("Every change is accounted for", func() {
+			So(context.Count(), ShouldEqual, workers*rounds/2)
+			So(group.Count(), ShouldEqual, 0)
+			total := 0
+			for w := 0; w < workers; w++ {
+				total += index.Count(float32(w))
+			}
+			So(total, ShouldEqual, workers*rounds/2)
+		})
+	})
+}
+
+func TestConcurrentListeners(t *testing.T) {
+	Convey("Given a concurrent context", t, func() {
+		context := NewContext(0, NumComponents, WithConcurrency())
+		group := context.Group(AllOf(ComponentA))
+
+		Convey("Listeners run after the change and may change the context", func() {
+			var sawB bool
+			group.AddEvent(EventAdded, func(g Group, e Entity, c Component) {
+				So(e.AddComponent(NewComponentB(1)), ShouldBeNil)
+				sawB = e.HasComponent(ComponentB)
+			})
+			e := context.CreateEntity(NewComponentA(1))
+
+			So(sawB, ShouldBeTrue)
+			So(e.HasComponent(ComponentB), ShouldBeTrue)
+		})
+
+		Convey("A listener removed before it ran is skipped", func() {
+			calls := 0
+			var second Subscription
+			group.AddEvent(EventAdded, func(g Group, e Entity, c Component) {
+				second.Unsubscribe()
+			})
+			second = group.AddEvent(EventAdded, func(g Group, e Entity, c Component) {
+				calls++
+			})
+			context.CreateEntity(NewComponentA(1))
+
+			So(calls, ShouldEqual, 0)
+		})
+
+		Convey("A removed component is pooled after its listeners ran", func() {
+			context.RegisterComponent(&componentA{})
+			var seen int
+			group.AddEvent(EventRemoved, func(g Group, e Entity, c Component) {
+				seen = c.(*componentA).value
+				So(context.ComponentPoolStats(ComponentA).Size, ShouldEqual, 0)
+			})
+			e := context.CreateEntity(NewComponentA(7))
+			e.RemoveComponent(ComponentA)
+
+			So(seen, ShouldEqual, 7)
+			So(context.ComponentPoolStats(ComponentA).Size, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestConcurrentReadsDuringChanges(t *testing.T) {
+	Convey("Given a concurrent context with a Where group and an index reading components", t, func() {
+		context := NewContext(0, NumComponents, WithConcurrency())
+		context.RegisterComponent(&componentA{})
+		context.RegisterComponent(&componentB{})
+		context.SetUnique(NewComponentC())
+
+		group := context.Group(Where("positive", func(e Entity) bool {
+			a, err := Get[*componentA](e)
+			_, hasParent := context.Parent(e)
+			return err == nil && a.value > 0 && context.HasUnique(ComponentC) && !hasParent
+		}, ComponentA))
+		index := NewEntityIndex(context, AllOf(ComponentB), func(e Entity) interface{} {
+			b, _ := Get[*componentB](e)
+			return b.value
+		})
+
+		done := make(chan bool)
+		go func() {
+			context.CreateEntity(NewComponentA(1), NewComponentB(2))
+			context.CreateEntity(NewComponentA(-1))
+			close(done)
+		}()
+		finished := false
+		select {
+		case <-done:
+			finished = true
+		case <-time.After(5 * time.Second):
+		}
+
+		Convey("Predicates and keys read without deadlocking", func() {
+			So(finished, ShouldBeTrue)
+			So(group.Count(), ShouldEqual, 1)
+			So(index.Count(float32(2)), ShouldEqual, 1)
+		})
+	})
+}
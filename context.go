@@ -2,11 +2,9 @@ package entitas
 
 import (
 	"fmt"
+	"io"
 	"reflect"
-)
-
-var (
-	TotalComponents int
+	"sync/atomic"
 )
 
 type ComponentNewFunc func() Component
@@ -21,207 +19,580 @@ const (
 	ContextEntityCreated ContextEntityEvent = iota
 	ContextEntityWillBeDestroyed
 	ContextEntityDestroyed
+	// ContextEntityParentChanged fires for an entity that got a new parent
+	// or was detached from its parent.
+	ContextEntityParentChanged
+	// ContextEntityChildrenChanged fires for an entity that gained or lost
+	// a child.
+	ContextEntityChildrenChanged
+)
+
+type ContextGroupEvent uint
+
+const (
+	ContextGroupCreated ContextGroupEvent = iota
+	// ContextGroupReleased fires when the last user released a group. It
+	// is already gone from the context but still holds its entities.
+	ContextGroupReleased
+	// ContextGroupCleared fires after a released group dropped its
+	// entities and event listeners.
+	ContextGroupCleared
 )
 
 type Context interface {
+	TotalComponents() int
 	CreateComponent(ts int) Component
+	ComponentPoolStats(ts int) ComponentPoolStats
+	SetComponentCleanup(t int, mode CleanupMode)
+	ComponentCleanup(t int) CleanupMode
 	RegisterComponent(component Component)
+	ComponentType(t reflect.Type) (int, bool)
 
 	CreateEntity(cs ...Component) Entity
 	Entities() []Entity
 	Count() int
+	ReusableCount() int
 	HasEntity(e Entity) bool
+	IsAlive(h EntityHandle) bool
+	Resolve(h EntityHandle) (Entity, bool)
+	RetainedEntities() []Entity
 	destroyEntity(e Entity)
+	releaseEntity(e Entity)
+	releaseGroup(g Group)
+	setAccessHook(hook func(t int, write bool))
+	componentAccessed(t int, write bool)
+	poolComponent(c Component)
 	DestroyAllEntities()
 	Group(matcher ...Matcher) Group
-
-	AddEvent(ContextEntityEvent, ContextEntityChanged)
-	AddGroupCreatedEvent(changed ContextGroupChanged)
+	Groups() []Group
+	ReleaseGroup(g Group)
+
+	SetUnique(c Component) (Entity, error)
+	ReplaceUnique(c Component) Entity
+	Unique(t int) (Component, error)
+	UniqueEntity(t int) (Entity, bool)
+	HasUnique(t int) bool
+	RemoveUnique(t int) error
+
+	SetParent(child, parent Entity) error
+	Parent(e Entity) (Entity, bool)
+	Children(e Entity) []Entity
+	Ancestors(e Entity) []Entity
+	SetHierarchyPolicy(e Entity, policy HierarchyPolicy)
+
+	Snapshot(w io.Writer) error
+	Restore(r io.Reader) error
+
+	AddEvent(ContextEntityEvent, ContextEntityChanged) Subscription
+	AddGroupCreatedEvent(changed ContextGroupChanged) Subscription
+	AddGroupEvent(event ContextGroupEvent, changed ContextGroupChanged) Subscription
 }
 
+type ContextOption func(*context)
+
 type context struct {
+	totalComponents int
+
 	index         EntityID
 	entities      map[EntityID]Entity
-	entitiesCache []Entity
+	entitiesCache atomic.Pointer[[]Entity]
 	unused        []Entity
+	retained      map[EntityID]Entity
+	retainDebug   bool
 
-	groups      map[uint]Group
+	groups      map[uint][]Group
+	groupCount  int
 	groupsIndex map[int][]Group
+	groupRefs   map[Group]int
+	uniques     []Entity
+	hierarchy   hierarchy
 
-	cacheComponents   [][]Component
+	pools             []componentPool
+	cleanups          []CleanupMode
 	registerComponent []reflect.Type
+	componentTypes    map[reflect.Type]int
 
-	entityChanged map[ContextEntityEvent][]ContextEntityChanged
-	groupChanged  []ContextGroupChanged
+	entityChanged handlers[ContextEntityEvent, ContextEntityChanged]
+	groupChanged  handlers[ContextGroupEvent, ContextGroupChanged]
+
+	accessHook atomic.Pointer[func(t int, write bool)]
+	internal   atomic.Int32
+
+	lock *contextLock
+}
+
+// WithRetainDebug makes entities of the context remember who retains
+// them, so Entity.Owners can report who still holds a destroyed entity.
+// Retaining twice or releasing without a retain by the same owner panics.
+func WithRetainDebug() ContextOption {
+	return func(p *context) {
+		p.retainDebug = true
+	}
 }
 
-func NewContext(index EntityID) Context {
-	if TotalComponents == 0 {
-		panic("please set entitas.TotalComponents")
+// WithConcurrency makes the context, its entities, groups, observers and
+// indices safe to use from several goroutines. Every change locks the
+// whole context, while reads of entities and groups only wait for a change
+// to be stored. Event listeners and component hooks run once the change
+// that fired them released the lock, so they may call back into the
+// context but may see it changed further by then. Matchers and index keys
+// run with the lock held and must only read.
+func WithConcurrency() ContextOption {
+	return func(p *context) {
+		p.lock = &contextLock{}
 	}
-	return &context{
+}
+
+// NewContext creates a context whose entities start at index and hold
+// components with types in [0, totalComponents).
+func NewContext(index EntityID, totalComponents int, options ...ContextOption) Context {
+	if totalComponents <= 0 {
+		panic("totalComponents must be positive")
+	}
+	p := &context{
+		totalComponents:   totalComponents,
 		index:             index,
 		entities:          make(map[EntityID]Entity),
-		groups:            make(map[uint]Group),
+		groups:            make(map[uint][]Group),
 		groupsIndex:       make(map[int][]Group),
+		groupRefs:         make(map[Group]int),
+		uniques:           make([]Entity, totalComponents),
+		hierarchy:         newHierarchy(),
 		unused:            make([]Entity, 0),
-		cacheComponents:   make([][]Component, TotalComponents),
-		registerComponent: make([]reflect.Type, TotalComponents),
-		entityChanged:     make(map[ContextEntityEvent][]ContextEntityChanged),
+		retained:          make(map[EntityID]Entity),
+		pools:             newComponentPools(totalComponents),
+		cleanups:          make([]CleanupMode, totalComponents),
+		registerComponent: make([]reflect.Type, totalComponents),
+		componentTypes:    make(map[reflect.Type]int),
+		entityChanged:     make(handlers[ContextEntityEvent, ContextEntityChanged]),
+		groupChanged:      make(handlers[ContextGroupEvent, ContextGroupChanged]),
+	}
+	for _, option := range options {
+		option(p)
 	}
+	return p
 }
 
 func (p *context) CreateComponent(ts int) (component Component) {
-	cache := p.cacheComponents[ts]
-	length := len(cache)
-	if length > 0 {
-		last := length - 1
-		component = cache[last]
-		p.cacheComponents[ts] = cache[:last]
-	} else {
-		value := reflect.New(p.registerComponent[ts])
-		component = value.Interface().(Component)
-	}
-	return
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.pools[ts].get(p.registerComponent[ts])
+}
+
+// ComponentPoolStats reports how the pool of component type ts is used.
+func (p *context) ComponentPoolStats(ts int) ComponentPoolStats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.pools[ts].stats()
+}
+
+func (p *context) TotalComponents() int {
+	return p.totalComponents
 }
 
 func (p *context) RegisterComponent(component Component) {
-	p.registerComponent[component.Type()] = reflect.TypeOf(component).Elem()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.inRange(component.Type()) {
+		panic(fmt.Sprintf("component type %d out of range [0, %d)", component.Type(), p.totalComponents))
+	}
+	t := reflect.TypeOf(component)
+	p.lock.beginWrite()
+	p.registerComponent[component.Type()] = t.Elem()
+	p.componentTypes[t] = component.Type()
+	p.lock.endWrite()
+	p.pools[component.Type()].fill(t.Elem())
+}
+
+// ComponentType returns the component type index registered for the Go
+// type t, which is the pointer type passed to RegisterComponent.
+func (p *context) ComponentType(t reflect.Type) (int, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	ts, ok := p.componentTypes[t]
+	return ts, ok
 }
 
 func (p *context) CreateEntity(cs ...Component) Entity {
-	e := p.getEntity()
-	e.AddComponent(cs...)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.addEntity(p.getEntity(), cs...)
+}
+
+func (p *context) addEntity(e Entity, cs ...Component) Entity {
+	e.addComponent(cs...)
+	p.lock.beginWrite()
 	p.entities[e.ID()] = e
-	if p.entitiesCache != nil {
-		p.entitiesCache = append(p.entitiesCache, e)
+	p.lock.endWrite()
+	if cache := p.entitiesCache.Load(); cache != nil {
+		entities := append(*cache, e)
+		p.entitiesCache.Store(&entities)
 	}
 
-	for _, g := range p.groups {
-		g.HandleEntity(e)
-	}
+	p.handleEntity(e, cs...)
 	return e
 }
 
+// Entities returns the entities of the context. Without concurrency the
+// slice is cached until an entity is destroyed; the cache is filled
+// atomically, so parallel systems may call it at the same time. With
+// concurrency every call returns a new slice.
 func (p *context) Entities() []Entity {
-	if p.entitiesCache == nil {
-		entities := make([]Entity, 0, len(p.entities))
+	p.lock.RLock()
+	defer p.lock.RUnlock()
 
-		for _, e := range p.entities {
-			entities = append(entities, e)
-		}
-		p.entitiesCache = entities
+	if cache := p.entitiesCache.Load(); cache != nil {
+		return *cache
+	}
+	entities := make([]Entity, 0, len(p.entities))
+	for _, e := range p.entities {
+		entities = append(entities, e)
 	}
-	return p.entitiesCache
+	if p.lock == nil {
+		p.entitiesCache.Store(&entities)
+	}
+	return entities
 }
 
 func (p *context) Count() int {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
 	return len(p.entities)
 }
 
+func (p *context) ReusableCount() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return len(p.unused)
+}
+
 func (p *context) HasEntity(e Entity) bool {
-	entity, exist := p.entities[e.ID()]
-	return exist && entity == e
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.hasEntity(e)
+}
+
+func (p *context) IsAlive(h EntityHandle) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	_, ok := p.resolve(h)
+	return ok
+}
+
+// Resolve returns the entity h refers to, unless it was destroyed.
+func (p *context) Resolve(h EntityHandle) (Entity, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.resolve(h)
 }
 
 func (p *context) destroyEntity(e Entity) {
-	if p.HasEntity(e) {
+	if p.hasEntity(e) {
 		p.onEntityChanged(ContextEntityWillBeDestroyed, e)
-		e.RemoveAllComponents()
-		e.RemoveAllEvents()
+		p.destroyHierarchy(e)
+		e.removeAllComponents()
+		e.removeAllEvents()
 		p.onEntityChanged(ContextEntityDestroyed, e)
 
+		p.lock.beginWrite()
 		delete(p.entities, e.ID())
+		p.lock.endWrite()
+		p.entitiesCache.Store(nil)
+
+		p.handleEntity(e)
+		e.kill()
 
-		p.entitiesCache = nil
-		for _, g := range p.groups {
-			g.HandleEntity(e)
+		if e.RetainCount() > 0 {
+			p.retained[e.ID()] = e
+		} else {
+			p.unused = append(p.unused, e)
 		}
-		p.unused = append(p.unused, e)
 	} else {
 		panic("unknown entity")
 	}
 }
 
+// releaseEntity returns a destroyed entity to the pool once its last
+// owner released it.
+func (p *context) releaseEntity(e Entity) {
+	if retained, ok := p.retained[e.ID()]; ok && retained == e {
+		delete(p.retained, e.ID())
+		p.unused = append(p.unused, e)
+	}
+}
+
 func (p *context) DestroyAllEntities() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
 	for _, e := range p.entities {
-		p.onEntityChanged(ContextEntityWillBeDestroyed, e)
-		e.internalDestroy()
-		p.onEntityChanged(ContextEntityDestroyed, e)
+		p.destroyEntity(e)
+	}
+}
+
+// RetainedEntities returns the destroyed entities that are kept out of the
+// pool because someone still retains them.
+func (p *context) RetainedEntities() []Entity {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	entities := make([]Entity, 0, len(p.retained))
+	for _, e := range p.retained {
+		entities = append(entities, e)
 	}
-	p.entities = make(map[EntityID]Entity)
-	p.entitiesCache = nil
+	return entities
 }
 
+// Group returns the group of entities matching all of matchers, creating
+// it on first use. Every call takes a reference to the group, which
+// ReleaseGroup gives back.
 func (p *context) Group(matchers ...Matcher) Group {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	matchers = compoundMatchers(matchers)
 	hash := HashMatcher(matchers...)
-	if g, ok := p.groups[hash]; ok {
-		return g
+	for _, g := range p.groups[hash] {
+		if equalMatchers(g.Matchers(), matchers) {
+			p.groupRefs[g]++
+			return g
+		}
 	}
 
-	g := newGroup(matchers...)
+	g := newGroup(p.lock, matchers...)
+	p.internal.Add(1)
 	for _, e := range p.entities {
-		g.HandleEntity(e)
+		g.handleEntity(e, nil)
 	}
-	p.groups[hash] = g
+	p.internal.Add(-1)
+	p.groups[hash] = append(p.groups[hash], g)
+	p.groupCount++
+	p.groupRefs[g] = 1
 
-	for _, m := range matchers {
-		for _, t := range m.ComponentTypes() {
-			p.groupsIndex[t] = append(p.groupsIndex[t], g)
-		}
+	for _, t := range groupTypes(g) {
+		p.groupsIndex[t] = append(p.groupsIndex[t], g)
 	}
 
-	p.onGroupChanged(g)
+	p.onGroupChanged(ContextGroupCreated, g)
 
 	return g
 }
 
-func (p *context) AddEvent(event ContextEntityEvent, action ContextEntityChanged) {
-	actions := p.entityChanged[event]
-	p.entityChanged[event] = append(actions, action)
+// ReleaseGroup gives back a reference taken by Group. Once no reference is
+// left, the group is removed from the context and stops following entity
+// changes, then it is cleared.
+func (p *context) ReleaseGroup(g Group) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.releaseGroup(g)
 }
 
-func (p *context) AddGroupCreatedEvent(changed ContextGroupChanged) {
+func (p *context) Groups() []Group {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	groups := make([]Group, 0, p.groupCount)
+	for _, bucket := range p.groups {
+		groups = append(groups, bucket...)
+	}
+	return groups
+}
+
+func (p *context) AddEvent(event ContextEntityEvent, action ContextEntityChanged) Subscription {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	h := p.entityChanged.add(event, action, true)
+	return newSubscription(p.lock, func() {
+		p.entityChanged.remove(event, h)
+	})
+}
 
+func (p *context) AddGroupCreatedEvent(changed ContextGroupChanged) Subscription {
+	return p.AddGroupEvent(ContextGroupCreated, changed)
+}
+
+func (p *context) AddGroupEvent(event ContextGroupEvent, changed ContextGroupChanged) Subscription {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	h := p.groupChanged.add(event, changed, true)
+	return newSubscription(p.lock, func() {
+		p.groupChanged.remove(event, h)
+	})
 }
 
 func (p *context) String() string {
-	return fmt.Sprintf("Context(%d entities, %d reusable, %d groups)",
-		len(p.entities), len(p.unused), len(p.groups))
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return fmt.Sprintf("Context(%d entities, %d reusable, %d retained, %d groups)",
+		len(p.entities), len(p.unused), len(p.retained), p.groupCount)
 }
 
 // private
+func (p *context) releaseGroup(g Group) {
+	refs, ok := p.groupRefs[g]
+	if !ok {
+		panic("unknown group")
+	}
+	if refs > 1 {
+		p.groupRefs[g] = refs - 1
+		return
+	}
+
+	delete(p.groupRefs, g)
+	hash := HashMatcher(g.Matchers()...)
+	if groups := withoutGroup(p.groups[hash], g); len(groups) == 0 {
+		delete(p.groups, hash)
+	} else {
+		p.groups[hash] = groups
+	}
+	p.groupCount--
+	for _, t := range groupTypes(g) {
+		if groups := withoutGroup(p.groupsIndex[t], g); len(groups) == 0 {
+			delete(p.groupsIndex, t)
+		} else {
+			p.groupsIndex[t] = groups
+		}
+	}
+
+	p.onGroupChanged(ContextGroupReleased, g)
+	g.clear()
+	p.onGroupChanged(ContextGroupCleared, g)
+}
+
+func (p *context) inRange(t int) bool {
+	return t >= 0 && t < p.totalComponents
+}
+
+func (p *context) hasEntity(e Entity) bool {
+	entity, exist := p.entities[e.ID()]
+	return exist && entity == e
+}
+
+func (p *context) resolve(h EntityHandle) (Entity, bool) {
+	e, ok := p.entities[h.ID]
+	if !ok || e.Handle().Generation != h.Generation {
+		return nil, false
+	}
+	return e, true
+}
+
+func (p *context) setAccessHook(hook func(t int, write bool)) {
+	if hook == nil {
+		p.accessHook.Store(nil)
+	} else {
+		p.accessHook.Store(&hook)
+	}
+}
+
+// componentAccessed reports an access to a component type to the access
+// hook. Reads the context does itself while it updates its groups are not
+// reported.
+func (p *context) componentAccessed(t int, write bool) {
+	if hook := p.accessHook.Load(); hook != nil && p.internal.Load() == 0 {
+		(*hook)(t, write)
+	}
+}
+
+// poolComponent keeps a removed component for CreateComponent. Entities
+// call it once every listener saw the removal; with concurrency it waits
+// for the listeners queued so far.
+func (p *context) poolComponent(c Component) {
+	if p.lock == nil {
+		p.pools[c.Type()].put(c)
+		return
+	}
+	p.lock.later(func() {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+
+		p.pools[c.Type()].put(c)
+	})
+}
+
 func (p *context) onEntityChanged(t ContextEntityEvent, entity Entity) {
-	events := p.entityChanged[t]
-	for _, event := range events {
+	external := p.entityChanged.each(t, p.lock, func(event ContextEntityChanged) {
 		event(p, entity)
+	})
+	if external != nil {
+		callLater(p.lock, external, func(event ContextEntityChanged) {
+			event(p, entity)
+		})
 	}
 }
 
-func (p *context) onGroupChanged(group Group) {
-	for _, event := range p.groupChanged {
+func (p *context) onGroupChanged(t ContextGroupEvent, group Group) {
+	external := p.groupChanged.each(t, p.lock, func(event ContextGroupChanged) {
 		event(p, group)
+	})
+	if external != nil {
+		callLater(p.lock, external, func(event ContextGroupChanged) {
+			event(p, group)
+		})
+	}
+}
+
+func causeOf(g Group, cs []Component) Component {
+	for i := len(cs) - 1; i >= 0; i-- {
+		for _, m := range g.Matchers() {
+			for _, t := range m.ComponentTypes() {
+				if t == cs[i].Type() {
+					return cs[i]
+				}
+			}
+		}
 	}
+	return nil
+}
+
+// withoutGroup returns groups without g. It doesn't change the array of
+// groups, which callers may be ranging over.
+func withoutGroup(groups []Group, g Group) []Group {
+	for i, other := range groups {
+		if other == g {
+			return append(groups[:i:i], groups[i+1:]...)
+		}
+	}
+	return groups
+}
+
+// groupTypes returns every component type the matchers of g look at.
+func groupTypes(g Group) []int {
+	var types []int
+	for _, m := range g.Matchers() {
+		types = append(types, m.ComponentTypes()...)
+	}
+	return typeSet(types)
 }
 
 func (p *context) componentAdded(e Entity, c Component) {
 	p.forMatchingGroup(e, c, func(g Group) {
-		g.HandleEntity(e)
+		g.handleEntity(e, c)
 	})
 }
 
 func (p *context) componentUpdated(e Entity, c Component) {
 	p.forMatchingGroup(e, c, func(g Group) {
-		g.UpdateEntity(e)
+		g.updateEntity(e, c)
 	})
 }
 
 func (p *context) componentRemoved(e Entity, c Component) {
-	t := c.Type()
-	p.cacheComponents[t] = append(p.cacheComponents[t], c)
-
 	p.forMatchingGroup(e, c, func(g Group) {
-		g.HandleEntity(e)
+		g.handleEntity(e, c)
 	})
 }
 
@@ -230,24 +601,44 @@ func (p *context) getEntity() (entity Entity) {
 	length := len(p.unused)
 	if length > 0 {
 		last := length - 1
-		entity = p.unused[last]
+		entity = p.unused[last].recycle()
+		p.unused[last] = nil
 		p.unused = p.unused[:last]
 	} else {
-		entity = newEntity(p, p.index)
+		entity = newEntity(p, p.index, 0)
 		p.index++
 	}
 
-	entity.AddEvent(EventAdded, p.componentAdded)
-	entity.AddEvent(EventUpdated, p.componentUpdated)
-	entity.AddEvent(EventRemoved, p.componentRemoved)
+	p.setupEntity(entity)
+	return entity
+}
+
+func (p *context) setupEntity(entity Entity) {
+	entity.addListener(EventAdded, p.componentAdded)
+	entity.addListener(EventUpdated, p.componentUpdated)
+	entity.addListener(EventRemoved, p.componentRemoved)
 
 	p.onEntityChanged(ContextEntityCreated, entity)
+}
 
-	return entity
+// handleEntity lets every group check e. cs are the components e was just
+// created with; each group is told about the last of them it looks at.
+func (p *context) handleEntity(e Entity, cs ...Component) {
+	p.internal.Add(1)
+	defer p.internal.Add(-1)
+
+	for _, bucket := range p.groups {
+		for _, g := range bucket {
+			g.handleEntity(e, causeOf(g, cs))
+		}
+	}
 }
 
 func (p *context) forMatchingGroup(e Entity, c Component, f func(g Group)) {
-	if p.HasEntity(e) {
+	p.internal.Add(1)
+	defer p.internal.Add(-1)
+
+	if p.hasEntity(e) {
 		for _, g := range p.groupsIndex[c.Type()] {
 			f(g)
 		}
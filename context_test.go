@@ -0,0 +1,169 @@
+package entitas
+
+import (
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestContextComponents(t *testing.T) {
+	Convey("Given two contexts with different component sets", t, func() {
+		game := NewContext(0, NumComponents)
+		input := NewContext(0, 2)
+		input.RegisterComponent(&componentA{})
+
+		Convey("Each keeps its own size and registry", func() {
+			So(game.TotalComponents(), ShouldEqual, NumComponents)
+			So(input.TotalComponents(), ShouldEqual, 2)
+
+			_, ok := TypeOf[*componentA](game)
+			So(ok, ShouldBeFalse)
+			_, ok = TypeOf[*componentA](input)
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("Types outside a context's set are rejected", func() {
+			So(func() { input.RegisterComponent(&componentC{}) }, ShouldPanic)
+			So(func() { NewContext(0, 0) }, ShouldPanic)
+
+			e := input.CreateEntity()
+			So(e.AddComponent(NewComponentC()), ShouldEqual, ErrComponentTypeOutOfRange)
+			So(e.UpdateComponent(NewComponentC()), ShouldEqual, ErrComponentTypeOutOfRange)
+			So(e.RemoveComponent(ComponentC), ShouldEqual, ErrComponentTypeOutOfRange)
+			So(e.HasComponent(ComponentC), ShouldBeFalse)
+			So(e.HasAnyComponent(ComponentA, ComponentC), ShouldBeFalse)
+			_, err := e.Component(ComponentC)
+			So(err, ShouldEqual, ErrComponentTypeOutOfRange)
+
+			_, err = input.SetUnique(NewComponentC())
+			So(err, ShouldEqual, ErrComponentTypeOutOfRange)
+			So(input.HasUnique(ComponentC), ShouldBeFalse)
+			So(input.RemoveUnique(ComponentC), ShouldEqual, ErrComponentDoesNotExist)
+		})
+	})
+}
+
+func TestContextGroups(t *testing.T) {
+	Convey("Given a context with group events", t, func() {
+		context := NewContext(0, NumComponents)
+		var events []string
+		context.AddGroupCreatedEvent(func(c Context, g Group) {
+			events = append(events, "created "+g.Matchers()[0].String())
+		})
+		context.AddGroupEvent(ContextGroupReleased, func(c Context, g Group) {
+			events = append(events, "released", fmt.Sprint(g.Count()))
+		})
+		context.AddGroupEvent(ContextGroupCleared, func(c Context, g Group) {
+			events = append(events, "cleared", fmt.Sprint(g.Count()))
+		})
+		context.CreateEntity(NewComponentA(1))
+
+		Convey("Creating a group fires once", func() {
+			g := context.Group(AllOf(ComponentA))
+			So(context.Group(AllOf(ComponentA)), ShouldEqual, g)
+			So(events, ShouldResemble, []string{"created AllOf([0])"})
+		})
+
+		Convey("A group is removed when its last reference is released", func() {
+			g := context.Group(AllOf(ComponentA))
+			context.Group(AllOf(ComponentA))
+
+			context.ReleaseGroup(g)
+			So(context.Groups(), ShouldResemble, []Group{g})
+
+			context.ReleaseGroup(g)
+			So(context.Groups(), ShouldBeEmpty)
+			So(events[1:], ShouldResemble, []string{"released", "1", "cleared", "0"})
+
+			Convey("It no longer follows entities", func() {
+				context.CreateEntity(NewComponentA(2))
+				So(g.Count(), ShouldEqual, 0)
+				So(context.Group(AllOf(ComponentA)), ShouldNotEqual, g)
+			})
+		})
+
+		Convey("Releasing an unknown group panics", func() {
+			g := context.Group(AllOf(ComponentA))
+			context.ReleaseGroup(g)
+			So(func() { context.ReleaseGroup(g) }, ShouldPanic)
+		})
+	})
+}
+
+type resettableComponent struct {
+	value int
+}
+
+func (c *resettableComponent) Type() int {
+	return ComponentE
+}
+
+func (c *resettableComponent) Reset() {
+	c.value = 0
+}
+
+func TestContextComponentPool(t *testing.T) {
+	Convey("Given a context pooling at most two components of type E", t, func() {
+		context := NewContext(0, NumComponents, WithComponentPool(ComponentE, 2, 1))
+		context.RegisterComponent(&resettableComponent{})
+
+		Convey("Registering preallocates", func() {
+			So(context.ComponentPoolStats(ComponentE), ShouldResemble, ComponentPoolStats{Size: 1})
+		})
+
+		Convey("Pooled components are reset and reused", func() {
+			c1 := context.CreateComponent(ComponentE).(*resettableComponent)
+			c2 := context.CreateComponent(ComponentE).(*resettableComponent)
+			c1.value = 7
+			e := context.CreateEntity(c1)
+			So(context.ComponentPoolStats(ComponentE), ShouldResemble, ComponentPoolStats{Hits: 1, Misses: 1})
+
+			var seen int
+			e.AddEvent(EventRemoved, func(e Entity, c Component) {
+				seen = c.(*resettableComponent).value
+			})
+			e.RemoveComponent(ComponentE)
+			So(seen, ShouldEqual, 7)
+			So(c1.value, ShouldEqual, 0)
+			So(context.CreateComponent(ComponentE), ShouldEqual, c1)
+
+			Convey("up to the pool capacity", func() {
+				for _, c := range []Component{c1, c2, &resettableComponent{}} {
+					e.AddComponent(c)
+					e.RemoveComponent(ComponentE)
+				}
+				So(context.ComponentPoolStats(ComponentE).Size, ShouldEqual, 2)
+			})
+		})
+	})
+}
+
+func TestContextCaches(t *testing.T) {
+	Convey("Given a context without concurrency", t, func() {
+		context := NewContext(0, NumComponents)
+		e := context.CreateEntity(NewComponentA(1), NewComponentB(2))
+
+		Convey("Reading entities and components again does not allocate", func() {
+			context.Entities()
+			e.Components()
+			e.ComponentTypes()
+			So(testing.AllocsPerRun(10, func() {
+				context.Entities()
+				e.Components()
+				e.ComponentTypes()
+			}), ShouldEqual, 0)
+		})
+
+		Convey("The caches follow changes", func() {
+			So(context.Entities(), ShouldHaveLength, 1)
+			other := context.CreateEntity()
+			So(context.Entities(), ShouldHaveLength, 2)
+			other.Destroy()
+			So(context.Entities(), ShouldResemble, []Entity{e})
+
+			So(e.ComponentTypes(), ShouldResemble, []int{ComponentA, ComponentB})
+			e.RemoveComponent(ComponentA)
+			So(e.ComponentTypes(), ShouldResemble, []int{ComponentB})
+		})
+	})
+}
@@ -0,0 +1,110 @@
+package debug
+
+const indexHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>entitas inspector</title>
+<style>
+body { font-family: monospace; margin: 1em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+td, th { border: 1px solid #ccc; padding: 2px 6px; text-align: left; vertical-align: top; }
+a { cursor: pointer; color: #06c; }
+input { font-family: monospace; width: 8em; }
+.error { color: #c00; }
+</style>
+</head>
+<body>
+<h1>entitas inspector</h1>
+<div id="contexts"></div>
+<h2 id="title"></h2>
+<div id="entities"></div>
+<p class="error" id="error"></p>
+<script>
+let current = null;
+
+function el(tag, text) {
+	const e = document.createElement(tag);
+	if (text !== undefined) e.textContent = text;
+	return e;
+}
+
+function row(table, cells) {
+	const tr = el("tr");
+	for (const c of cells) {
+		const td = el("td");
+		if (c instanceof Node) td.appendChild(c); else td.textContent = c;
+		tr.appendChild(td);
+	}
+	table.appendChild(tr);
+}
+
+function showError(err) {
+	document.getElementById("error").textContent = err;
+}
+
+async function loadContexts() {
+	const contexts = await (await fetch("contexts")).json();
+	const table = el("table");
+	row(table, ["context", "entities", "reusable", "retained", "groups"]);
+	for (const c of contexts) {
+		const link = el("a", c.name);
+		link.onclick = () => { current = c.name; loadEntities(); };
+		const groups = c.groups.map(g => g.matchers.join(" ") + " (" + g.count + ")").join("\n");
+		const pre = el("pre", groups);
+		row(table, [link, c.entities, c.reusable, c.retained, pre]);
+	}
+	const div = document.getElementById("contexts");
+	div.replaceChildren(table);
+}
+
+async function loadEntities() {
+	document.getElementById("title").textContent = current;
+	const entities = await (await fetch("entities?context=" + encodeURIComponent(current))).json();
+	const table = el("table");
+	row(table, ["entity", "components"]);
+	for (const e of entities) {
+		const cell = el("div");
+		for (const c of e.components) {
+			cell.appendChild(el("b", c.name));
+			for (const f of c.fields) {
+				const line = el("div");
+				line.appendChild(el("span", " " + f.name + " (" + f.type + "): "));
+				if (f.editable) {
+					const input = el("input");
+					input.value = JSON.stringify(f.value);
+					input.onchange = () => edit(e, c, f, input.value);
+					line.appendChild(input);
+				} else {
+					line.appendChild(el("span", f.value));
+				}
+				cell.appendChild(line);
+			}
+		}
+		row(table, [e.id + "." + e.generation, cell]);
+	}
+	document.getElementById("entities").replaceChildren(table);
+}
+
+async function edit(e, c, f, value) {
+	showError("");
+	const url = "component?context=" + encodeURIComponent(current) +
+		"&entity=" + e.id + "&generation=" + e.generation + "&type=" + c.type;
+	let body;
+	try {
+		body = JSON.stringify({field: f.name, value: JSON.parse(value)});
+	} catch (err) {
+		showError(err);
+		return;
+	}
+	const res = await fetch(url, {method: "POST", headers: {"Content-Type": "application/json"}, body: body});
+	if (!res.ok) showError((await res.json()).error);
+	loadContexts();
+	loadEntities();
+}
+
+loadContexts();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,322 @@
+// Package debug serves a live view of entitas contexts over HTTP.
+//
+// Mount an Inspector on a local address, for example
+//
+//	inspector := debug.NewInspector()
+//	inspector.AddContext("game", context)
+//	http.Handle("/debug/entitas/", http.StripPrefix("/debug/entitas", inspector))
+//
+// and open /debug/entitas/ in a browser. The Inspector reads and edits
+// component fields in place, which no lock of the context covers, so when
+// the simulation runs on other goroutines give the Inspector the lock it
+// holds while it runs with SetLocker, whether or not the context was
+// created WithConcurrency.
+//
+// Edits must be sent as application/json. Browsers send that content type
+// cross-origin only after a CORS preflight, which the Inspector never
+// answers, so other web pages can't change the simulation.
+package debug
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+
+	entitas "github.com/jangsky215/go-entitas"
+)
+
+var (
+	ErrUnknownContext = errors.New("unknown context")
+	ErrUnknownEntity  = errors.New("unknown entity")
+	ErrUnknownField   = errors.New("unknown or unexported field")
+	ErrNotJSON        = errors.New("content type must be application/json")
+)
+
+type Inspector struct {
+	mu       sync.Mutex
+	names    []string
+	contexts map[string]entitas.Context
+	locker   sync.Locker
+	mux      *http.ServeMux
+}
+
+func NewInspector() *Inspector {
+	i := &Inspector{
+		contexts: make(map[string]entitas.Context),
+		locker:   noLocker{},
+		mux:      http.NewServeMux(),
+	}
+	i.mux.HandleFunc("/", i.serveIndex)
+	i.mux.HandleFunc("/contexts", i.serveContexts)
+	i.mux.HandleFunc("/entities", i.serveEntities)
+	i.mux.HandleFunc("/component", i.serveComponent)
+	return i
+}
+
+func (i *Inspector) AddContext(name string, context entitas.Context) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if _, ok := i.contexts[name]; !ok {
+		i.names = append(i.names, name)
+	}
+	i.contexts[name] = context
+}
+
+// SetLocker makes the Inspector hold l while it reads or edits a context.
+// Without it the Inspector races with any goroutine changing components.
+func (i *Inspector) SetLocker(l sync.Locker) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.locker = l
+}
+
+func (i *Inspector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	i.mux.ServeHTTP(w, r)
+}
+
+type contextInfo struct {
+	Name     string      `json:"name"`
+	Entities int         `json:"entities"`
+	Reusable int         `json:"reusable"`
+	Retained int         `json:"retained"`
+	Groups   []groupInfo `json:"groups"`
+}
+
+type groupInfo struct {
+	Matchers []string `json:"matchers"`
+	Count    int      `json:"count"`
+}
+
+type entityInfo struct {
+	ID         entitas.EntityID `json:"id"`
+	Generation uint32           `json:"generation"`
+	Components []componentInfo  `json:"components"`
+}
+
+type componentInfo struct {
+	Type   int         `json:"type"`
+	Name   string      `json:"name"`
+	Fields []fieldInfo `json:"fields"`
+}
+
+type fieldInfo struct {
+	Name     string      `json:"name"`
+	Type     string      `json:"type"`
+	Value    interface{} `json:"value"`
+	Editable bool        `json:"editable"`
+}
+
+type fieldEdit struct {
+	Field string          `json:"field"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (i *Inspector) serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, indexHTML)
+}
+
+func (i *Inspector) serveContexts(w http.ResponseWriter, r *http.Request) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.locker.Lock()
+	defer i.locker.Unlock()
+
+	infos := make([]contextInfo, 0, len(i.names))
+	for _, name := range i.names {
+		context := i.contexts[name]
+		info := contextInfo{
+			Name:     name,
+			Entities: context.Count(),
+			Reusable: context.ReusableCount(),
+			Retained: len(context.RetainedEntities()),
+			Groups:   make([]groupInfo, 0),
+		}
+		for _, g := range context.Groups() {
+			gi := groupInfo{Count: g.Count()}
+			for _, m := range g.Matchers() {
+				gi.Matchers = append(gi.Matchers, m.String())
+			}
+			info.Groups = append(info.Groups, gi)
+		}
+		sort.Slice(info.Groups, func(a, b int) bool {
+			return fmt.Sprint(info.Groups[a].Matchers) < fmt.Sprint(info.Groups[b].Matchers)
+		})
+		infos = append(infos, info)
+	}
+	writeJSON(w, infos)
+}
+
+func (i *Inspector) serveEntities(w http.ResponseWriter, r *http.Request) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	context, ok := i.contexts[r.URL.Query().Get("context")]
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrUnknownContext)
+		return
+	}
+
+	i.locker.Lock()
+	defer i.locker.Unlock()
+
+	infos := make([]entityInfo, 0, context.Count())
+	for _, e := range context.Entities() {
+		info := entityInfo{
+			ID:         e.ID(),
+			Generation: e.Handle().Generation,
+			Components: make([]componentInfo, 0),
+		}
+		for _, t := range e.ComponentTypes() {
+			c, _ := e.Component(t)
+			info.Components = append(info.Components, inspectComponent(t, c))
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(a, b int) bool {
+		return infos[a].ID < infos[b].ID
+	})
+	writeJSON(w, infos)
+}
+
+// serveComponent edits one exported field of a component. The entity is
+// told through UpdateComponent, so groups and observers see the change.
+func (i *Inspector) serveComponent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, ErrNotJSON)
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	query := r.URL.Query()
+	context, ok := i.contexts[query.Get("context")]
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrUnknownContext)
+		return
+	}
+	id, err1 := strconv.ParseUint(query.Get("entity"), 10, 64)
+	generation, err2 := strconv.ParseUint(query.Get("generation"), 10, 32)
+	t, err3 := strconv.Atoi(query.Get("type"))
+	if err := firstError(err1, err2, err3); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var edit fieldEdit
+	if err := json.NewDecoder(r.Body).Decode(&edit); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	i.locker.Lock()
+	defer i.locker.Unlock()
+
+	e, ok := context.Resolve(entitas.EntityHandle{ID: entitas.EntityID(id), Generation: uint32(generation)})
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrUnknownEntity)
+		return
+	}
+	c, err := e.Component(t)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err := setField(c, edit); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := e.UpdateComponent(c); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, inspectComponent(t, c))
+}
+
+func inspectComponent(t int, c entitas.Component) componentInfo {
+	v := reflect.Indirect(reflect.ValueOf(c))
+	info := componentInfo{
+		Type:   t,
+		Name:   v.Type().Name(),
+		Fields: make([]fieldInfo, 0),
+	}
+	if v.Kind() != reflect.Struct {
+		return info
+	}
+
+	for n := 0; n < v.NumField(); n++ {
+		sf := v.Type().Field(n)
+		fi := fieldInfo{
+			Name:     sf.Name,
+			Type:     sf.Type.String(),
+			Editable: sf.PkgPath == "",
+		}
+		if fi.Editable {
+			fi.Value = v.Field(n).Interface()
+		} else {
+			fi.Value = fmt.Sprint(v.Field(n))
+		}
+		info.Fields = append(info.Fields, fi)
+	}
+	return info
+}
+
+func setField(c entitas.Component, edit fieldEdit) error {
+	v := reflect.Indirect(reflect.ValueOf(c))
+	if v.Kind() != reflect.Struct {
+		return ErrUnknownField
+	}
+	field := v.FieldByName(edit.Field)
+	if !field.IsValid() || !field.CanSet() {
+		return fmt.Errorf("%w: %s", ErrUnknownField, edit.Field)
+	}
+
+	value := reflect.New(field.Type())
+	if err := json.Unmarshal(edit.Value, value.Interface()); err != nil {
+		return err
+	}
+	field.Set(value.Elem())
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type noLocker struct{}
+
+func (noLocker) Lock()   {}
+func (noLocker) Unlock() {}
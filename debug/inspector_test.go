@@ -0,0 +1,95 @@
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	entitas "github.com/jangsky215/go-entitas"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type health struct {
+	Value int
+	max   int
+}
+
+func (h *health) Type() int {
+	return 0
+}
+
+func TestInspector(t *testing.T) {
+	Convey("Given an inspector over a context", t, func() {
+		context := entitas.NewContext(0, 1)
+		context.RegisterComponent(&health{})
+		group := context.Group(entitas.AllOf(0))
+		e := context.CreateEntity(&health{Value: 10, max: 20})
+
+		inspector := NewInspector()
+		inspector.AddContext("game", context)
+
+		get := func(url string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			inspector.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+			return w
+		}
+
+		Convey("It serves the HTML page", func() {
+			w := get("/")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldContainSubstring, "entitas inspector")
+		})
+
+		Convey("It lists contexts with their groups", func() {
+			var infos []contextInfo
+			So(json.NewDecoder(get("/contexts").Body).Decode(&infos), ShouldBeNil)
+			So(infos, ShouldHaveLength, 1)
+			So(infos[0].Name, ShouldEqual, "game")
+			So(infos[0].Entities, ShouldEqual, 1)
+			So(infos[0].Groups, ShouldResemble, []groupInfo{{Matchers: []string{"AllOf([0])"}, Count: 1}})
+		})
+
+		Convey("It lists entities with their component fields", func() {
+			var infos []entityInfo
+			So(json.NewDecoder(get("/entities?context=game").Body).Decode(&infos), ShouldBeNil)
+			So(infos, ShouldHaveLength, 1)
+			fields := infos[0].Components[0].Fields
+			So(fields[0], ShouldResemble, fieldInfo{Name: "Value", Type: "int", Value: 10.0, Editable: true})
+			So(fields[1], ShouldResemble, fieldInfo{Name: "max", Type: "int", Value: "20", Editable: false})
+
+			So(get("/entities?context=input").Code, ShouldEqual, http.StatusNotFound)
+		})
+
+		Convey("It edits fields through UpdateComponent", func() {
+			updated := 0
+			group.AddEvent(entitas.EventUpdated, func(entitas.Group, entitas.Entity, entitas.Component) {
+				updated++
+			})
+
+			postAs := func(contentType, field string) int {
+				w := httptest.NewRecorder()
+				body := strings.NewReader(`{"field":"` + field + `","value":3}`)
+				r := httptest.NewRequest(http.MethodPost, "/component?context=game&entity=0&generation=0&type=0", body)
+				r.Header.Set("Content-Type", contentType)
+				inspector.ServeHTTP(w, r)
+				return w.Code
+			}
+			post := func(field string) int {
+				return postAs("application/json", field)
+			}
+
+			So(postAs("text/plain", "Value"), ShouldEqual, http.StatusUnsupportedMediaType)
+			So(postAs("", "Value"), ShouldEqual, http.StatusUnsupportedMediaType)
+			So(updated, ShouldEqual, 0)
+
+			So(post("Value"), ShouldEqual, http.StatusOK)
+			h, _ := entitas.Get[*health](e)
+			So(h.Value, ShouldEqual, 3)
+			So(updated, ShouldEqual, 1)
+
+			So(post("max"), ShouldEqual, http.StatusBadRequest)
+		})
+	})
+}
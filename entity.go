@@ -3,25 +3,42 @@ package entitas
 import (
 	"errors"
 	"fmt"
+	"sync/atomic"
 )
 
 var (
-	ErrComponentExists       = errors.New("component exists")
-	ErrComponentDoesNotExist = errors.New("component does not exist")
+	ErrComponentExists         = errors.New("component exists")
+	ErrComponentDoesNotExist   = errors.New("component does not exist")
+	ErrComponentNotRegistered  = errors.New("component is not registered")
+	ErrComponentTypeOutOfRange = errors.New("component type is out of the context's range")
+	ErrEntityAlreadyRetained   = errors.New("entity is already retained by owner")
+	ErrEntityNotRetained       = errors.New("entity is not retained by owner")
+	ErrEntityDestroyed         = errors.New("entity is destroyed")
 )
 
 type EntityComponentChanged func(Entity, Component)
 
 type EntityID uint64
 
+// EntityHandle identifies one logical entity. Pooled entities keep their
+// EntityID but get a new generation, so a saved handle of a destroyed
+// entity never resolves to the entity that reuses its ID.
+type EntityHandle struct {
+	ID         EntityID
+	Generation uint32
+}
+
 type Entity interface {
 	ID() EntityID
+	Handle() EntityHandle
+	IsAlive() bool
+	Context() Context
 
 	CreateComponent(ts int) Component
 	AddComponent(cs ...Component) error
-	UpdateComponent(cs ...Component)
+	UpdateComponent(cs ...Component) error
 	RemoveComponent(ts ...int) error
-	RemoveAllComponents()
+	RemoveAllComponents() error
 
 	HasComponent(ts ...int) bool
 	HasAnyComponent(ts ...int) bool
@@ -29,50 +46,133 @@ type Entity interface {
 	Components() []Component
 	ComponentTypes() []int
 
-	AddEvent(ev EventType, action EntityComponentChanged)
+	AddEvent(ev EventType, action EntityComponentChanged) Subscription
 	RemoveAllEvents()
 	HasEvents() bool
 
-	Destroy()
-	internalDestroy()
+	Retain(owner interface{})
+	Release(owner interface{})
+	RetainCount() int
+	Owners() []interface{}
+
+	Destroy() error
+	recycle() Entity
+	kill()
+
+	addComponent(cs ...Component) error
+	updateComponent(cs ...Component) error
+	removeAllComponents()
+	addListener(ev EventType, action EntityComponentChanged)
+	removeAllEvents()
+	retain(owner interface{})
+	release(owner interface{})
+	component(t int) Component
 }
 
 type entity struct {
 	id               EntityID
+	generation       uint32
+	alive            bool
 	components       []Component
-	componentChanged map[EventType][]EntityComponentChanged
+	componentChanged handlers[EventType, EntityComponentChanged]
+
+	componentsCache     atomic.Pointer[[]Component]
+	componentTypesCache atomic.Pointer[[]int]
 
-	componentsCache     []Component
-	componentTypesCache []int
+	retainCount int
+	owners      map[interface{}]bool
 
 	context Context
+	lock    *contextLock
 }
 
-func newEntity(context Context, id EntityID) Entity {
-	return &entity{
+func newEntity(context *context, id EntityID, generation uint32) Entity {
+	e := &entity{
 		id:               id,
-		components:       make([]Component, TotalComponents),
-		componentChanged: make(map[EventType][]EntityComponentChanged),
+		generation:       generation,
+		alive:            true,
+		components:       make([]Component, context.totalComponents),
+		componentChanged: make(handlers[EventType, EntityComponentChanged]),
 		context:          context,
+		lock:             context.lock,
+	}
+	if context.retainDebug {
+		e.owners = make(map[interface{}]bool)
+	}
+	return e
+}
+
+// private
+func (e *entity) onAdded(c Component) {
+	if hook, ok := c.(ComponentAddedHook); ok {
+		if e.lock == nil {
+			hook.OnAdded(e)
+		} else {
+			e.lock.later(func() { hook.OnAdded(e) })
+		}
+	}
+}
+
+func (e *entity) onRemoved(c Component) {
+	if hook, ok := c.(ComponentRemovedHook); ok {
+		if e.lock == nil {
+			hook.OnRemoved(e)
+		} else {
+			e.lock.later(func() { hook.OnRemoved(e) })
+		}
+	}
+}
+
+func (e *entity) onReplaced(c, old Component) {
+	if hook, ok := c.(ComponentReplacedHook); ok {
+		if e.lock == nil {
+			hook.OnReplaced(e, old)
+		} else {
+			e.lock.later(func() { hook.OnReplaced(e, old) })
+		}
 	}
 }
 
-//private
 func (e *entity) onComponentChanged(ev EventType, c Component) {
-	if actions, ok := e.componentChanged[ev]; ok {
-		for _, action := range actions {
+	external := e.componentChanged.each(ev, e.lock, func(action EntityComponentChanged) {
+		action(e, c)
+	})
+	if external != nil {
+		callLater(e.lock, external, func(action EntityComponentChanged) {
 			action(e, c)
-		}
+		})
 	}
 }
 
-//public
+func (e *entity) setComponent(t int, c Component) {
+	e.lock.beginWrite()
+	e.components[t] = c
+	e.lock.endWrite()
+	e.dropCaches()
+}
+
+func (e *entity) dropCaches() {
+	e.componentsCache.Store(nil)
+	e.componentTypesCache.Store(nil)
+}
+
+// public
 func (e *entity) CreateComponent(ts int) Component {
 	return e.context.CreateComponent(ts)
 }
 
 func (e *entity) HasComponent(ts ...int) bool {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	if !e.alive {
+		return false
+	}
 	for _, t := range ts {
+		if !e.inRange(t) {
+			return false
+		}
+		e.context.componentAccessed(t, false)
 		if e.components[t] == nil {
 			return false
 		}
@@ -81,7 +181,17 @@ func (e *entity) HasComponent(ts ...int) bool {
 }
 
 func (e *entity) HasAnyComponent(ts ...int) bool {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	if !e.alive {
+		return false
+	}
 	for _, t := range ts {
+		if !e.inRange(t) {
+			continue
+		}
+		e.context.componentAccessed(t, false)
 		if e.components[t] != nil {
 			return true
 		}
@@ -90,6 +200,16 @@ func (e *entity) HasAnyComponent(ts ...int) bool {
 }
 
 func (e *entity) Component(t int) (Component, error) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	if !e.alive {
+		return nil, ErrEntityDestroyed
+	}
+	if !e.inRange(t) {
+		return nil, ErrComponentTypeOutOfRange
+	}
+	e.context.componentAccessed(t, false)
 	c := e.components[t]
 	if c == nil {
 		return nil, ErrComponentDoesNotExist
@@ -97,132 +217,360 @@ func (e *entity) Component(t int) (Component, error) {
 	return c, nil
 }
 
+// Components returns the components of e. Without concurrency the slice
+// is cached until e changes; the cache is filled atomically, so parallel
+// systems may call it at the same time. With concurrency every call
+// returns a new slice.
 func (e *entity) Components() []Component {
-	components := e.componentsCache
-	if components == nil {
-		components = make([]Component, 0, len(e.components))
-
-		for _, c := range e.components {
-			components = append(components, c)
-		}
+	e.lock.RLock()
+	defer e.lock.RUnlock()
 
-		e.componentsCache = components
+	if cache := e.componentsCache.Load(); cache != nil {
+		return *cache
+	}
+	components := make([]Component, 0, len(e.components))
+	for _, c := range e.components {
+		components = append(components, c)
+	}
+	if e.lock == nil {
+		e.componentsCache.Store(&components)
 	}
 	return components
 }
 
 func (e *entity) ComponentTypes() []int {
-	types := e.componentTypesCache
-	if types == nil {
-		types = make([]int, 0, len(e.components))
-		for t, c := range e.components {
-			if c != nil {
-				types = append(types, int(t))
-			}
-		}
-		e.componentTypesCache = types
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	if cache := e.componentTypesCache.Load(); cache != nil {
+		return *cache
+	}
+	types := e.componentTypes()
+	if e.lock == nil {
+		e.componentTypesCache.Store(&types)
 	}
 	return types
 }
 
 func (e *entity) AddComponent(cs ...Component) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return e.addComponent(cs...)
+}
+
+func (e *entity) UpdateComponent(cs ...Component) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return e.updateComponent(cs...)
+}
+
+func (e *entity) RemoveComponent(ts ...int) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if !e.alive {
+		return ErrEntityDestroyed
+	}
+	for _, t := range ts {
+		if !e.inRange(t) {
+			return ErrComponentTypeOutOfRange
+		}
+		e.context.componentAccessed(t, true)
+		c := e.components[t]
+		if c == nil {
+			return ErrComponentDoesNotExist
+		}
+		e.setComponent(t, nil)
+		e.onRemoved(c)
+		e.onComponentChanged(EventRemoved, c)
+		e.context.poolComponent(c)
+	}
+
+	return nil
+}
+
+func (e *entity) RemoveAllComponents() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if !e.alive {
+		return ErrEntityDestroyed
+	}
+	e.removeAllComponents()
+	return nil
+}
+
+func (e *entity) ID() EntityID {
+	return e.id
+}
+
+func (e *entity) Handle() EntityHandle {
+	return EntityHandle{e.id, e.generation}
+}
+
+// IsAlive reports whether e is neither destroyed nor recycled. Every
+// mutating method of a dead entity returns ErrEntityDestroyed.
+func (e *entity) IsAlive() bool {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	return e.alive
+}
+
+func (e *entity) Context() Context {
+	return e.context
+}
+
+func (e *entity) AddEvent(ev EventType, action EntityComponentChanged) Subscription {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if !e.alive {
+		return newSubscription(e.lock, func() {})
+	}
+	h := e.componentChanged.add(ev, action, true)
+	return newSubscription(e.lock, func() {
+		e.componentChanged.remove(ev, h)
+	})
+}
+
+func (e *entity) HasEvents() bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return len(e.componentChanged) > 0
+}
+
+// RemoveAllEvents detaches every listener, including the ones the
+// context keeps its groups up to date with. Use the Subscription returned
+// by AddEvent to detach a single listener.
+func (e *entity) RemoveAllEvents() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.removeAllEvents()
+}
+
+// Retain marks owner as holding on to e. A destroyed entity only goes
+// back to the pool of its context once every owner released it.
+func (e *entity) Retain(owner interface{}) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.retain(owner)
+}
+
+func (e *entity) Release(owner interface{}) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.release(owner)
+}
+
+func (e *entity) RetainCount() int {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	return e.retainCount
+}
+
+// Owners returns who retains e. It is only tracked when the context was
+// created WithRetainDebug.
+func (e *entity) Owners() []interface{} {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	owners := make([]interface{}, 0, len(e.owners))
+	for owner := range e.owners {
+		owners = append(owners, owner)
+	}
+	return owners
+}
+
+func (e *entity) Destroy() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if !e.alive {
+		return ErrEntityDestroyed
+	}
+	e.context.destroyEntity(e)
+	return nil
+}
+
+func (e *entity) String() string {
+	return fmt.Sprintf("Entity_%d(types %v)", e.id, e.ComponentTypes())
+}
+
+// helpers for callers holding the lock
+func (e *entity) componentTypes() []int {
+	types := make([]int, 0, len(e.components))
+	for t, c := range e.components {
+		if c != nil {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// inRange reports whether t is a component type of the context of the
+// live entity e.
+func (e *entity) inRange(t int) bool {
+	return t >= 0 && t < len(e.components)
+}
+
+// component returns the component of type t, or nil if e has none or is
+// dead, for callers holding either lock.
+func (e *entity) component(t int) Component {
+	if !e.alive || !e.inRange(t) {
+		return nil
+	}
+	return e.components[t]
+}
+
+func (e *entity) addComponent(cs ...Component) error {
+	if !e.alive {
+		return ErrEntityDestroyed
+	}
 	for _, c := range cs {
 		t := c.Type()
-		if e.HasComponent(t) {
+		if !e.inRange(t) {
+			return ErrComponentTypeOutOfRange
+		}
+		e.context.componentAccessed(t, true)
+		if e.components[t] != nil {
 			return ErrComponentExists
 		}
-		e.components[t] = c
+		e.setComponent(t, c)
+		e.onAdded(c)
 		e.onComponentChanged(EventAdded, c)
 	}
 
-	if len(cs) > 0 {
-		e.componentsCache = nil
-		e.componentTypesCache = nil
-	}
-
 	return nil
 }
 
-func (e *entity) UpdateComponent(cs ...Component) {
+func (e *entity) updateComponent(cs ...Component) error {
+	if !e.alive {
+		return ErrEntityDestroyed
+	}
 	for _, c := range cs {
 		t := c.Type()
+		if !e.inRange(t) {
+			return ErrComponentTypeOutOfRange
+		}
+		e.context.componentAccessed(t, true)
 		old := e.components[t]
-		e.components[t] = c
+		e.setComponent(t, c)
 		if old != nil {
+			if old != c {
+				e.onRemoved(old)
+				e.onAdded(c)
+			}
+			e.onReplaced(c, old)
 			if old != c {
 				e.onComponentChanged(EventRemoved, old)
 			}
 			e.onComponentChanged(EventUpdated, c)
+			if old != c {
+				e.context.poolComponent(old)
+			}
 		} else {
+			e.onAdded(c)
 			e.onComponentChanged(EventAdded, c)
 		}
 	}
 
-	if len(cs) > 0 {
-		e.componentsCache = nil
-		e.componentTypesCache = nil
-	}
-}
-
-func (e *entity) RemoveComponent(ts ...int) error {
-	for _, t := range ts {
-		c, err := e.Component(t)
-		if err != nil {
-			return err
-		}
-		e.components[t] = nil
-		e.onComponentChanged(EventRemoved, c)
-	}
-
-	if len(ts) > 0 {
-		e.componentsCache = nil
-		e.componentTypesCache = nil
-	}
-
 	return nil
 }
 
-func (e *entity) RemoveAllComponents() {
+func (e *entity) removeAllComponents() {
 	components := e.components
 
-	e.components = make([]Component, TotalComponents)
-	e.componentsCache = nil
-	e.componentTypesCache = nil
+	e.lock.beginWrite()
+	e.components = make([]Component, len(components))
+	e.lock.endWrite()
+	e.dropCaches()
 
-	for _, c := range components {
+	for t, c := range components {
 		if c != nil {
+			e.context.componentAccessed(t, true)
+			e.onRemoved(c)
 			e.onComponentChanged(EventRemoved, c)
+			e.context.poolComponent(c)
 		}
 	}
-
 }
 
-func (e *entity) ID() EntityID {
-	return e.id
+// addListener adds a listener of the package, which runs during the
+// dispatch even with concurrency.
+func (e *entity) addListener(ev EventType, action EntityComponentChanged) {
+	e.componentChanged.add(ev, action, false)
 }
 
-func (e *entity) AddEvent(ev EventType, action EntityComponentChanged) {
-	actions := e.componentChanged[ev]
-	e.componentChanged[ev] = append(actions, action)
+func (e *entity) removeAllEvents() {
+	e.componentChanged.clear()
 }
 
-func (e *entity) HasEvents() bool {
-	return len(e.componentChanged) > 0
+func (e *entity) retain(owner interface{}) {
+	if e.owners != nil {
+		if e.owners[owner] {
+			panic(ErrEntityAlreadyRetained)
+		}
+		e.owners[owner] = true
+	}
+	e.lock.beginWrite()
+	e.retainCount++
+	e.lock.endWrite()
 }
 
-func (e *entity) RemoveAllEvents() {
-	e.componentChanged = make(map[EventType][]EntityComponentChanged)
-}
+func (e *entity) release(owner interface{}) {
+	if e.owners != nil {
+		if !e.owners[owner] {
+			panic(ErrEntityNotRetained)
+		}
+		delete(e.owners, owner)
+	}
+	if e.retainCount == 0 {
+		panic(ErrEntityNotRetained)
+	}
 
-func (e *entity) Destroy() {
-	e.context.destroyEntity(e)
+	e.lock.beginWrite()
+	e.retainCount--
+	e.lock.endWrite()
+	if e.retainCount == 0 {
+		e.context.releaseEntity(e)
+	}
 }
 
-func (e *entity) internalDestroy() {
-	e.RemoveAllComponents()
-	e.RemoveAllEvents()
+// kill marks e as destroyed once its context is done tearing it down.
+func (e *entity) kill() {
+	e.lock.beginWrite()
+	e.alive = false
+	e.lock.endWrite()
 }
 
-func (e *entity) String() string {
-	return fmt.Sprintf("Entity_%d(types %v)", e.id, e.ComponentTypes())
+// recycle hands the storage of the destroyed e to a new entity with the
+// same ID and the next generation. e itself stays dead, so stale holders
+// can't change the entity that reuses it.
+func (e *entity) recycle() Entity {
+	entity := &entity{
+		id:               e.id,
+		generation:       e.generation + 1,
+		alive:            true,
+		components:       e.components,
+		componentChanged: e.componentChanged,
+		context:          e.context,
+		lock:             e.lock,
+	}
+	if e.owners != nil {
+		entity.owners = make(map[interface{}]bool)
+	}
+	e.lock.beginWrite()
+	e.components = nil
+	e.lock.endWrite()
+	e.dropCaches()
+	e.componentChanged = nil
+	return entity
 }
@@ -0,0 +1,354 @@
+package entitas
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrEntityIndexDuplicateKey = errors.New("entity index key exists")
+)
+
+type EntityIndexKey func(Entity) interface{}
+
+type PrimaryEntityIndex interface {
+	Entity(key interface{}) (Entity, bool)
+	HasEntity(key interface{}) bool
+	Keys() []interface{}
+	Err() error
+	Release()
+}
+
+type EntityIndex interface {
+	Entities(key interface{}) []Entity
+	Count(key interface{}) int
+	Keys() []interface{}
+	Release()
+}
+
+// baseEntityIndex keeps the key every indexed entity was filed under, so an
+// entity can be dropped from the index after the components its key was
+// computed from are already gone.
+type baseEntityIndex struct {
+	context       Context
+	group         Group
+	key           EntityIndexKey
+	keys          map[EntityID]interface{}
+	subscriptions []*subscription
+	lock          *contextLock
+}
+
+func newBaseEntityIndex(context Context, matcher Matcher, key EntityIndexKey) baseEntityIndex {
+	group := context.Group(matcher)
+	return baseEntityIndex{
+		context: context,
+		group:   group,
+		key:     key,
+		keys:    make(map[EntityID]interface{}),
+		lock:    group.mutex(),
+	}
+}
+
+func (index *baseEntityIndex) subscribe(added, updated, removed GroupChanged) {
+	index.subscriptions = []*subscription{
+		index.group.listen(EventAdded, added),
+		index.group.listen(EventUpdated, updated),
+		index.group.listen(EventRemoved, removed),
+	}
+}
+
+// release detaches the index from its group and gives the group back to
+// the context. It reports false if the index was released already.
+func (index *baseEntityIndex) release() bool {
+	if index.group == nil {
+		return false
+	}
+	for _, s := range index.subscriptions {
+		s.cancel()
+	}
+	index.subscriptions = nil
+	index.context.releaseGroup(index.group)
+	index.group = nil
+	return true
+}
+
+// PrimaryEntityIndex
+type primaryEntityIndex struct {
+	baseEntityIndex
+	entities map[interface{}]Entity
+	rejected map[EntityID]Entity
+}
+
+// NewPrimaryEntityIndex indexes every entity of context matching matcher by
+// a unique key. It returns ErrEntityIndexDuplicateKey if two entities
+// already share a key. An entity that later gets a key already taken is
+// rejected: it stays out of the index, and Err reports it, until the key
+// is free again.
+func NewPrimaryEntityIndex(context Context, matcher Matcher, key EntityIndexKey) (PrimaryEntityIndex, error) {
+	index := &primaryEntityIndex{
+		baseEntityIndex: newBaseEntityIndex(context, matcher, key),
+		entities:        make(map[interface{}]Entity),
+		rejected:        make(map[EntityID]Entity),
+	}
+	index.lock.Lock()
+	defer index.lock.Unlock()
+
+	for _, e := range index.group.Entities() {
+		if err := index.addEntity(e); err != nil {
+			index.releaseAll()
+			return nil, err
+		}
+	}
+	index.subscribe(index.onEntityAdded, index.onEntityUpdated, index.onEntityRemoved)
+
+	return index, nil
+}
+
+func (index *primaryEntityIndex) Entity(key interface{}) (Entity, bool) {
+	index.lock.RLock()
+	defer index.lock.RUnlock()
+
+	e, ok := index.entities[key]
+	return e, ok
+}
+
+func (index *primaryEntityIndex) HasEntity(key interface{}) bool {
+	index.lock.RLock()
+	defer index.lock.RUnlock()
+
+	_, ok := index.entities[key]
+	return ok
+}
+
+func (index *primaryEntityIndex) Keys() []interface{} {
+	index.lock.RLock()
+	defer index.lock.RUnlock()
+
+	keys := make([]interface{}, 0, len(index.entities))
+	for key := range index.entities {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Err returns an error wrapping ErrEntityIndexDuplicateKey while entities
+// are rejected for a key another entity holds.
+func (index *primaryEntityIndex) Err() error {
+	index.lock.RLock()
+	defer index.lock.RUnlock()
+
+	if len(index.rejected) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %d entities rejected", ErrEntityIndexDuplicateKey, len(index.rejected))
+}
+
+// Release stops the index and releases its entities and its group.
+func (index *primaryEntityIndex) Release() {
+	index.lock.Lock()
+	defer index.lock.Unlock()
+
+	index.releaseAll()
+}
+
+func (index *primaryEntityIndex) String() string {
+	index.lock.Lock()
+	defer index.lock.Unlock()
+
+	return fmt.Sprintf("PrimaryEntityIndex(%d keys)", len(index.entities))
+}
+
+// private
+func (index *primaryEntityIndex) addEntity(e Entity) error {
+	key := index.key(e)
+	if other, ok := index.entities[key]; ok && other != e {
+		return fmt.Errorf("%w: %v", ErrEntityIndexDuplicateKey, key)
+	}
+	index.lock.beginWrite()
+	index.entities[key] = e
+	index.keys[e.ID()] = key
+	index.lock.endWrite()
+	e.retain(index)
+	return nil
+}
+
+// removeEntity drops e and indexes an entity rejected for its key instead.
+func (index *primaryEntityIndex) removeEntity(e Entity) {
+	index.lock.beginWrite()
+	delete(index.rejected, e.ID())
+	key, ok := index.keys[e.ID()]
+	if ok {
+		delete(index.entities, key)
+		delete(index.keys, e.ID())
+	}
+	index.lock.endWrite()
+	if !ok {
+		return
+	}
+	e.release(index)
+
+	for id, rejected := range index.rejected {
+		if index.key(rejected) == key {
+			index.lock.beginWrite()
+			delete(index.rejected, id)
+			index.lock.endWrite()
+			index.addEntity(rejected)
+			return
+		}
+	}
+}
+
+func (index *primaryEntityIndex) releaseAll() {
+	if !index.release() {
+		return
+	}
+	for _, e := range index.entities {
+		e.release(index)
+	}
+	index.lock.beginWrite()
+	index.entities = make(map[interface{}]Entity)
+	index.keys = make(map[EntityID]interface{})
+	index.rejected = make(map[EntityID]Entity)
+	index.lock.endWrite()
+}
+
+func (index *primaryEntityIndex) onEntityAdded(g Group, e Entity, c Component) {
+	if err := index.addEntity(e); err != nil {
+		index.lock.beginWrite()
+		index.rejected[e.ID()] = e
+		index.lock.endWrite()
+	}
+}
+
+func (index *primaryEntityIndex) onEntityUpdated(g Group, e Entity, c Component) {
+	index.removeEntity(e)
+	index.onEntityAdded(g, e, c)
+}
+
+func (index *primaryEntityIndex) onEntityRemoved(g Group, e Entity, c Component) {
+	index.removeEntity(e)
+}
+
+// EntityIndex
+type entityIndex struct {
+	baseEntityIndex
+	entities map[interface{}]map[EntityID]Entity
+}
+
+// NewEntityIndex indexes every entity of context matching matcher by a key
+// several entities may share.
+func NewEntityIndex(context Context, matcher Matcher, key EntityIndexKey) EntityIndex {
+	index := &entityIndex{
+		baseEntityIndex: newBaseEntityIndex(context, matcher, key),
+		entities:        make(map[interface{}]map[EntityID]Entity),
+	}
+	index.lock.Lock()
+	defer index.lock.Unlock()
+
+	for _, e := range index.group.Entities() {
+		index.addEntity(e)
+	}
+	index.subscribe(index.onEntityAdded, index.onEntityUpdated, index.onEntityRemoved)
+
+	return index
+}
+
+func (index *entityIndex) Entities(key interface{}) []Entity {
+	index.lock.RLock()
+	defer index.lock.RUnlock()
+
+	set := index.entities[key]
+	entities := make([]Entity, 0, len(set))
+	for _, e := range set {
+		entities = append(entities, e)
+	}
+	return entities
+}
+
+func (index *entityIndex) Count(key interface{}) int {
+	index.lock.RLock()
+	defer index.lock.RUnlock()
+
+	return len(index.entities[key])
+}
+
+func (index *entityIndex) Keys() []interface{} {
+	index.lock.RLock()
+	defer index.lock.RUnlock()
+
+	keys := make([]interface{}, 0, len(index.entities))
+	for key := range index.entities {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Release stops the index and releases its entities and its group.
+func (index *entityIndex) Release() {
+	index.lock.Lock()
+	defer index.lock.Unlock()
+
+	if !index.release() {
+		return
+	}
+	for _, set := range index.entities {
+		for _, e := range set {
+			e.release(index)
+		}
+	}
+	index.lock.beginWrite()
+	index.entities = make(map[interface{}]map[EntityID]Entity)
+	index.keys = make(map[EntityID]interface{})
+	index.lock.endWrite()
+}
+
+func (index *entityIndex) String() string {
+	index.lock.Lock()
+	defer index.lock.Unlock()
+
+	return fmt.Sprintf("EntityIndex(%d keys)", len(index.entities))
+}
+
+// private
+func (index *entityIndex) addEntity(e Entity) {
+	key := index.key(e)
+	index.lock.beginWrite()
+	set, ok := index.entities[key]
+	if !ok {
+		set = make(map[EntityID]Entity)
+		index.entities[key] = set
+	}
+	set[e.ID()] = e
+	index.keys[e.ID()] = key
+	index.lock.endWrite()
+	e.retain(index)
+}
+
+func (index *entityIndex) removeEntity(e Entity) {
+	key, ok := index.keys[e.ID()]
+	if !ok {
+		return
+	}
+	index.lock.beginWrite()
+	delete(index.keys, e.ID())
+	set := index.entities[key]
+	delete(set, e.ID())
+	if len(set) == 0 {
+		delete(index.entities, key)
+	}
+	index.lock.endWrite()
+	e.release(index)
+}
+
+func (index *entityIndex) onEntityAdded(g Group, e Entity, c Component) {
+	index.addEntity(e)
+}
+
+func (index *entityIndex) onEntityUpdated(g Group, e Entity, c Component) {
+	index.removeEntity(e)
+	index.addEntity(e)
+}
+
+func (index *entityIndex) onEntityRemoved(g Group, e Entity, c Component) {
+	index.removeEntity(e)
+}
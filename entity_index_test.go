@@ -0,0 +1,89 @@
+package entitas
+
+import (
+	"errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func componentAValue(e Entity) interface{} {
+	c, _ := e.Component(ComponentA)
+	return c.(*componentA).value
+}
+
+func TestEntityIndex(t *testing.T) {
+	Convey("Given a context with entities holding component A", t, func() {
+		context := NewContext(0, NumComponents)
+		e1 := context.CreateEntity(NewComponentA(1))
+		e2 := context.CreateEntity(NewComponentA(2), NewComponentB(0))
+
+		Convey("A primary index finds each entity by its key", func() {
+			index, err := NewPrimaryEntityIndex(context, AllOf(ComponentA), componentAValue)
+			So(err, ShouldBeNil)
+
+			e, ok := index.Entity(1)
+			So(ok, ShouldBeTrue)
+			So(e, ShouldEqual, e1)
+			So(index.HasEntity(3), ShouldBeFalse)
+
+			Convey("It follows component updates and removals", func() {
+				e3 := context.CreateEntity(NewComponentA(3))
+				So(index.HasEntity(3), ShouldBeTrue)
+
+				e1.UpdateComponent(NewComponentA(4))
+				So(index.HasEntity(1), ShouldBeFalse)
+				e, _ = index.Entity(4)
+				So(e, ShouldEqual, e1)
+
+				e3.Destroy()
+				So(index.HasEntity(3), ShouldBeFalse)
+			})
+
+			Convey("An entity with a duplicate key is rejected until the key is free", func() {
+				e3 := context.CreateEntity(NewComponentA(2), NewComponentC())
+				So(e3.HasComponent(ComponentC), ShouldBeTrue)
+				So(errors.Is(index.Err(), ErrEntityIndexDuplicateKey), ShouldBeTrue)
+				e, _ = index.Entity(2)
+				So(e, ShouldEqual, e2)
+
+				e2.Destroy()
+				So(index.Err(), ShouldBeNil)
+				e, _ = index.Entity(2)
+				So(e, ShouldEqual, e3)
+			})
+
+			Convey("Releasing it releases its entities and its group", func() {
+				index.Release()
+				index.Release()
+				So(e1.RetainCount(), ShouldEqual, 0)
+				So(context.Groups(), ShouldBeEmpty)
+				So(index.HasEntity(1), ShouldBeFalse)
+			})
+		})
+
+		Convey("A primary index over duplicate keys returns an error", func() {
+			context.CreateEntity(NewComponentA(2))
+			_, err := NewPrimaryEntityIndex(context, AllOf(ComponentA), componentAValue)
+			So(errors.Is(err, ErrEntityIndexDuplicateKey), ShouldBeTrue)
+			So(context.Groups(), ShouldBeEmpty)
+			So(e1.RetainCount(), ShouldEqual, 0)
+		})
+
+		Convey("An entity index groups entities sharing a key", func() {
+			index := NewEntityIndex(context, AllOf(ComponentA), componentAValue)
+			e3 := context.CreateEntity(NewComponentA(2))
+
+			So(index.Count(2), ShouldEqual, 2)
+			So(index.Entities(2), ShouldContain, e2)
+			So(index.Entities(2), ShouldContain, e3)
+
+			e2.RemoveComponent(ComponentA)
+			So(index.Entities(2), ShouldResemble, []Entity{e3})
+			So(index.Count(5), ShouldEqual, 0)
+
+			index.Release()
+			So(e3.RetainCount(), ShouldEqual, 0)
+			So(context.Groups(), ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,163 @@
+package entitas
+
+import (
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestEntityRetain(t *testing.T) {
+	Convey("Given a context in retain debug mode", t, func() {
+		context := NewContext(0, NumComponents, WithRetainDebug())
+		e := context.CreateEntity(NewComponentA(1))
+
+		Convey("A retained entity is not reused after it is destroyed", func() {
+			e.Retain("cache")
+			e.Destroy()
+
+			So(context.RetainedEntities(), ShouldResemble, []Entity{e})
+			So(e.Owners(), ShouldResemble, []interface{}{"cache"})
+			So(context.CreateEntity(), ShouldNotEqual, e)
+
+			Convey("It returns to the pool once released", func() {
+				e.Release("cache")
+				So(context.RetainedEntities(), ShouldBeEmpty)
+				So(context.CreateEntity().ID(), ShouldEqual, e.ID())
+			})
+		})
+
+		Convey("Observers retain the entities they collected", func() {
+			observer := NewGroupObserver(context.Group(AllOf(ComponentA)), EventRemoved)
+			e.Destroy()
+			So(e.RetainCount(), ShouldEqual, 1)
+
+			observer.ClearCollectedEntities()
+			So(e.RetainCount(), ShouldEqual, 0)
+			So(context.RetainedEntities(), ShouldBeEmpty)
+		})
+
+		Convey("Releasing without retaining panics", func() {
+			So(func() { e.Release("cache") }, ShouldPanicWith, ErrEntityNotRetained)
+			e.Retain("cache")
+			So(func() { e.Retain("cache") }, ShouldPanicWith, ErrEntityAlreadyRetained)
+		})
+	})
+}
+
+func TestEntityGeneration(t *testing.T) {
+	Convey("Given a destroyed entity whose ID was reused", t, func() {
+		context := NewContext(0, NumComponents)
+		e := context.CreateEntity(NewComponentA(1))
+		h := e.Handle()
+		e.Destroy()
+		reused := context.CreateEntity(NewComponentB(0))
+
+		Convey("The reused entity has the same ID and a new generation", func() {
+			So(reused.ID(), ShouldEqual, e.ID())
+			So(reused.Handle().Generation, ShouldEqual, h.Generation+1)
+		})
+
+		Convey("The old handle no longer resolves", func() {
+			So(context.IsAlive(h), ShouldBeFalse)
+			_, ok := context.Resolve(h)
+			So(ok, ShouldBeFalse)
+
+			resolved, ok := context.Resolve(reused.Handle())
+			So(ok, ShouldBeTrue)
+			So(resolved, ShouldEqual, reused)
+		})
+
+		Convey("The old entity refuses to change the reused one", func() {
+			So(e.IsAlive(), ShouldBeFalse)
+			So(e.AddComponent(NewComponentC()), ShouldEqual, ErrEntityDestroyed)
+			So(e.UpdateComponent(NewComponentA(2)), ShouldEqual, ErrEntityDestroyed)
+			So(e.RemoveComponent(ComponentB), ShouldEqual, ErrEntityDestroyed)
+			So(e.RemoveAllComponents(), ShouldEqual, ErrEntityDestroyed)
+			So(e.Destroy(), ShouldEqual, ErrEntityDestroyed)
+			So(e.HasComponent(ComponentB), ShouldBeFalse)
+
+			So(reused.ComponentTypes(), ShouldResemble, []int{ComponentB})
+			So(context.Count(), ShouldEqual, 1)
+		})
+	})
+}
+
+type hookedComponent struct {
+	log      *[]string
+	id       string
+	observer GroupObserver
+}
+
+func (c *hookedComponent) Type() int {
+	return ComponentF
+}
+
+func (c *hookedComponent) OnAdded(e Entity) {
+	*c.log = append(*c.log, "added "+c.id)
+	if c.observer != nil {
+		*c.log = append(*c.log, fmt.Sprintf("observed %d", len(c.observer.CollectedEntities())))
+	}
+}
+
+func (c *hookedComponent) OnRemoved(e Entity) {
+	*c.log = append(*c.log, "removed "+c.id)
+}
+
+func (c *hookedComponent) OnReplaced(e Entity, old Component) {
+	*c.log = append(*c.log, "replaced "+old.(*hookedComponent).id+" by "+c.id)
+}
+
+func TestEntityComponentHooks(t *testing.T) {
+	for _, concurrent := range []bool{false, true} {
+		var options []ContextOption
+		name := "Given an entity and a group listening to component F"
+		if concurrent {
+			options = append(options, WithConcurrency())
+			name += " in a concurrent context"
+		}
+
+		Convey(name, t, func() {
+			var log []string
+			context := NewContext(0, NumComponents, options...)
+			group := context.Group(AllOf(ComponentF))
+			for _, ev := range []EventType{EventAdded, EventUpdated, EventRemoved} {
+				ev := ev
+				group.AddEvent(ev, func(g Group, e Entity, c Component) {
+					log = append(log, fmt.Sprintf("event %d %s", ev, c.(*hookedComponent).id))
+				})
+			}
+			e := context.CreateEntity()
+			c1 := &hookedComponent{log: &log, id: "1"}
+			c2 := &hookedComponent{log: &log, id: "2"}
+
+			Convey("Hooks run before the group events", func() {
+				e.AddComponent(c1)
+				e.UpdateComponent(c1)
+				e.UpdateComponent(c2)
+				e.RemoveComponent(ComponentF)
+				e.AddComponent(c1)
+				e.RemoveAllComponents()
+
+				So(log, ShouldResemble, []string{
+					"added 1", "event 0 1",
+					"replaced 1 by 1", "event 1 1",
+					"removed 1", "added 2", "replaced 1 by 2", "event 1 2",
+					"removed 2", "event 2 2",
+					"added 1", "event 0 1",
+					"removed 1", "event 2 1",
+				})
+			})
+
+			Convey("Observers see the change before the hook only with concurrency", func() {
+				c1.observer = NewGroupObserver(group, EventAdded)
+				e.AddComponent(c1)
+
+				observed := "observed 0"
+				if concurrent {
+					observed = "observed 1"
+				}
+				So(log, ShouldResemble, []string{"added 1", observed, "event 0 1"})
+			})
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package entitas
+
+import "sync/atomic"
+
+// Subscription is returned by every AddEvent. Unsubscribe detaches the
+// handler; calling it again does nothing.
+type Subscription interface {
+	Unsubscribe()
+}
+
+type subscription struct {
+	done   bool
+	lock   *contextLock
+	remove func()
+}
+
+func newSubscription(lock *contextLock, remove func()) *subscription {
+	return &subscription{lock: lock, remove: remove}
+}
+
+func (s *subscription) Unsubscribe() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.cancel()
+}
+
+// cancel is Unsubscribe for callers holding the lock.
+func (s *subscription) cancel() {
+	if !s.done {
+		s.done = true
+		s.remove()
+	}
+}
+
+// private
+type handler[F any] struct {
+	fn       F
+	external bool
+	removed  atomic.Bool
+}
+
+// handlers holds the listeners of each event. A slice is never changed in
+// place once a dispatch may be ranging over it, and removed handlers are
+// flagged, so listeners can unsubscribe themselves or each other while an
+// event is being dispatched.
+type handlers[K comparable, F any] map[K][]*handler[F]
+
+// add registers fn for k. External handlers, added through AddEvent, run
+// after the lock is released; the others belong to the package and run
+// during the dispatch.
+func (hs handlers[K, F]) add(k K, fn F, external bool) *handler[F] {
+	h := &handler[F]{fn: fn, external: external}
+	list := hs[k]
+	hs[k] = append(list[:len(list):len(list)], h)
+	return h
+}
+
+func (hs handlers[K, F]) remove(k K, h *handler[F]) {
+	h.removed.Store(true)
+	list := hs[k]
+	for i, other := range list {
+		if other == h {
+			if len(list) == 1 {
+				delete(hs, k)
+			} else {
+				hs[k] = append(list[:i:i], list[i+1:]...)
+			}
+			return
+		}
+	}
+}
+
+func (hs handlers[K, F]) clear() {
+	for k, list := range hs {
+		for _, h := range list {
+			h.removed.Store(true)
+		}
+		delete(hs, k)
+	}
+}
+
+// each calls the handlers of k. With concurrency, the external ones are
+// left out and returned, for the caller to pass to later.
+func (hs handlers[K, F]) each(k K, lock *contextLock, call func(F)) []*handler[F] {
+	var external []*handler[F]
+	for _, h := range hs[k] {
+		switch {
+		case h.removed.Load():
+		case lock != nil && h.external:
+			external = append(external, h)
+		default:
+			call(h.fn)
+		}
+	}
+	return external
+}
+
+// callLater calls the handlers each left out, unless they were removed in
+// the meantime.
+func callLater[F any](lock *contextLock, external []*handler[F], call func(F)) {
+	lock.later(func() {
+		for _, h := range external {
+			if !h.removed.Load() {
+				call(h.fn)
+			}
+		}
+	})
+}
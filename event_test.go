@@ -0,0 +1,70 @@
+package entitas
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestSubscription(t *testing.T) {
+	Convey("Given a context, an entity and a group", t, func() {
+		context := NewContext(0, NumComponents)
+		e := context.CreateEntity()
+		group := context.Group(AllOf(ComponentA))
+
+		Convey("Unsubscribed entity handlers are no longer called", func() {
+			count := 0
+			s := e.AddEvent(EventAdded, func(Entity, Component) { count++ })
+			e.AddComponent(NewComponentA(1))
+			s.Unsubscribe()
+			s.Unsubscribe()
+			e.RemoveComponent(ComponentA)
+			e.AddComponent(NewComponentA(2))
+			So(count, ShouldEqual, 1)
+
+			Convey("while the context's listeners stay attached", func() {
+				So(group.ContainsEntity(e), ShouldBeTrue)
+			})
+		})
+
+		Convey("Unsubscribed context handlers are no longer called", func() {
+			count := 0
+			s := context.AddEvent(ContextEntityCreated, func(Context, Entity) { count++ })
+			context.CreateEntity()
+			s.Unsubscribe()
+			context.CreateEntity()
+			So(count, ShouldEqual, 1)
+		})
+
+		Convey("Handlers may unsubscribe during dispatch", func() {
+			var calls []string
+			var first, second Subscription
+			first = group.AddEvent(EventAdded, func(Group, Entity, Component) {
+				calls = append(calls, "first")
+				first.Unsubscribe()
+				second.Unsubscribe()
+			})
+			second = group.AddEvent(EventAdded, func(Group, Entity, Component) {
+				calls = append(calls, "second")
+			})
+			group.AddEvent(EventAdded, func(Group, Entity, Component) {
+				calls = append(calls, "third")
+			})
+
+			e.AddComponent(NewComponentA(1))
+			context.CreateEntity(NewComponentA(2))
+			So(calls, ShouldResemble, []string{"first", "third", "third"})
+		})
+
+		Convey("A deactivated observer detaches from its group", func() {
+			observer := NewGroupObserver(group, EventAdded)
+			observer.Deactivate()
+			e.AddComponent(NewComponentA(1))
+			So(observer.CollectedEntities(), ShouldBeEmpty)
+
+			observer.Activate()
+			observer.Activate()
+			context.CreateEntity(NewComponentA(2))
+			So(len(observer.CollectedEntities()), ShouldEqual, 1)
+		})
+	})
+}
@@ -1,60 +1,95 @@
 package entitas
 
-type GroupChanged func(Group, Entity)
+import "sync/atomic"
+
+// GroupChanged receives the component whose change caused the event. It is
+// nil when the entity was already there as the group was created, or when
+// no component of the group's types was involved.
+type GroupChanged func(Group, Entity, Component)
 
 type Group interface {
 	Entities() []Entity
-	HandleEntity(e Entity)
-	UpdateEntity(e Entity)
+	Count() int
+	Matchers() []Matcher
+	HandleEntity(e Entity, c Component)
+	UpdateEntity(e Entity, c Component)
 	Matches(e Entity) bool
 	ContainsEntity(e Entity) bool
 
-	AddEvent(EventType, GroupChanged)
+	AddEvent(EventType, GroupChanged) Subscription
 	RemoveAllEvents()
+
+	mutex() *contextLock
+	handleEntity(e Entity, c Component)
+	updateEntity(e Entity, c Component)
+	listen(ev EventType, action GroupChanged) *subscription
+	clear()
 }
 
+// group stores its entities as a sparse set: entities is dense and index
+// maps an entity ID to its position in it. Removing moves the last entity
+// into the freed slot. Once Entities has handed out the dense slice, the
+// next removal copies it first, so callers can keep iterating a slice that
+// never changes under them.
+//
+// The copy is the price of that: removing any number of entities while
+// iterating costs one copy, but alternating one Entities call with one
+// removal copies the whole group every time, as BenchmarkGroupChurn shows.
+// Code that does so should collect what it removes and remove it after
+// iterating, or check Count and ContainsEntity instead.
 type group struct {
-	entities         map[EntityID]Entity
-	cache            []Entity
-	cacheInvalidated bool
-	matchers         []Matcher
+	entities []Entity
+	index    map[EntityID]int
+	shared   atomic.Bool
+	matchers []Matcher
+
+	groupChanged handlers[EventType, GroupChanged]
 
-	groupChanged map[EventType][]GroupChanged
+	lock *contextLock
 }
 
-func newGroup(matchers ...Matcher) Group {
+func newGroup(lock *contextLock, matchers ...Matcher) Group {
 	return &group{
-		entities:     make(map[EntityID]Entity),
+		index:        make(map[EntityID]int),
 		matchers:     matchers,
-		groupChanged: make(map[EventType][]GroupChanged),
+		groupChanged: make(handlers[EventType, GroupChanged]),
+		lock:         lock,
 	}
 }
 
+// Entities returns the entities of the group. The slice must not be
+// changed, but it stays valid while the group changes.
 func (g *group) Entities() []Entity {
-	cache := g.cache
-	if cache == nil {
-		cache = make([]Entity, 0, len(g.entities))
+	g.lock.RLock()
+	defer g.lock.RUnlock()
 
-		for _, e := range g.entities {
-			cache = append(cache, e)
-		}
-		g.cache = cache
-	}
-	return cache
+	g.shared.Store(true)
+	return g.entities
 }
 
-func (g *group) HandleEntity(e Entity) {
-	if g.Matches(e) {
-		g.addEntity(e)
-	} else {
-		g.removeEntity(e)
-	}
+func (g *group) Count() int {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	return len(g.entities)
 }
 
-func (g *group) UpdateEntity(e Entity) {
-	if _, ok := g.entities[e.ID()]; ok {
-		g.onGroupChanged(EventUpdated, e)
-	}
+func (g *group) Matchers() []Matcher {
+	return g.matchers
+}
+
+func (g *group) HandleEntity(e Entity, c Component) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.handleEntity(e, c)
+}
+
+func (g *group) UpdateEntity(e Entity, c Component) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.updateEntity(e, c)
 }
 
 func (g *group) Matches(e Entity) bool {
@@ -67,42 +102,119 @@ func (g *group) Matches(e Entity) bool {
 }
 
 func (g *group) ContainsEntity(e Entity) bool {
-	_, exist := g.entities[e.ID()]
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	_, exist := g.index[e.ID()]
 	return exist
 }
 
-func (g *group) AddEvent(event EventType, action GroupChanged) {
-	actions := g.groupChanged[event]
-	g.groupChanged[event] = append(actions, action)
+func (g *group) AddEvent(event EventType, action GroupChanged) Subscription {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	return g.addEvent(event, action, true)
 }
 
 func (g *group) RemoveAllEvents() {
-	g.groupChanged = make(map[EventType][]GroupChanged)
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.groupChanged.clear()
 }
 
 // private
-func (g *group) onGroupChanged(ev EventType, e Entity) {
-	if events, ok := g.groupChanged[ev]; ok {
-		for _, event := range events {
-			event(g, e)
-		}
+func (g *group) mutex() *contextLock {
+	return g.lock
+}
+
+func (g *group) handleEntity(e Entity, c Component) {
+	if g.Matches(e) {
+		g.addEntity(e, c)
+	} else {
+		g.removeEntity(e, c)
 	}
 }
 
-func (g *group) addEntity(e Entity) {
-	if _, ok := g.entities[e.ID()]; !ok {
-		g.entities[e.ID()] = e
-		if g.cache != nil {
-			g.cache = append(g.cache, e)
-		}
-		g.onGroupChanged(EventAdded, e)
+func (g *group) updateEntity(e Entity, c Component) {
+	_, ok := g.index[e.ID()]
+	switch matches := g.Matches(e); {
+	case ok && matches:
+		g.onGroupChanged(EventUpdated, e, c)
+	case ok:
+		g.removeEntity(e, c)
+	case matches:
+		g.addEntity(e, c)
 	}
 }
 
-func (g *group) removeEntity(e Entity) {
-	if _, ok := g.entities[e.ID()]; ok {
-		delete(g.entities, e.ID())
-		g.cache = nil
-		g.onGroupChanged(EventRemoved, e)
+// listen adds a listener of the package, which runs during the dispatch
+// even with concurrency.
+func (g *group) listen(event EventType, action GroupChanged) *subscription {
+	return g.addEvent(event, action, false)
+}
+
+func (g *group) addEvent(event EventType, action GroupChanged, external bool) *subscription {
+	h := g.groupChanged.add(event, action, external)
+	return newSubscription(g.lock, func() {
+		g.groupChanged.remove(event, h)
+	})
+}
+
+// clear drops the entities and listeners of a group released by its
+// context, without firing removed events.
+func (g *group) clear() {
+	g.lock.beginWrite()
+	g.entities = nil
+	g.index = make(map[EntityID]int)
+	g.shared.Store(false)
+	g.lock.endWrite()
+	g.groupChanged.clear()
+}
+
+func (g *group) onGroupChanged(ev EventType, e Entity, c Component) {
+	external := g.groupChanged.each(ev, g.lock, func(event GroupChanged) {
+		event(g, e, c)
+	})
+	if external != nil {
+		callLater(g.lock, external, func(event GroupChanged) {
+			event(g, e, c)
+		})
 	}
 }
+
+func (g *group) addEntity(e Entity, c Component) {
+	if _, ok := g.index[e.ID()]; !ok {
+		g.lock.beginWrite()
+		g.index[e.ID()] = len(g.entities)
+		g.entities = append(g.entities, e)
+		g.lock.endWrite()
+		g.onGroupChanged(EventAdded, e, c)
+	}
+}
+
+func (g *group) removeEntity(e Entity, c Component) {
+	i, ok := g.index[e.ID()]
+	if !ok {
+		return
+	}
+
+	g.lock.beginWrite()
+	if g.shared.Load() {
+		g.entities = append(make([]Entity, 0, cap(g.entities)), g.entities...)
+		g.shared.Store(false)
+	}
+
+	last := len(g.entities) - 1
+	if i != last {
+		moved := g.entities[last]
+		g.entities[i] = moved
+		g.index[moved.ID()] = i
+	}
+	g.entities[last] = nil
+	g.entities = g.entities[:last]
+	delete(g.index, e.ID())
+	g.lock.endWrite()
+
+	g.onGroupChanged(EventRemoved, e, c)
+}
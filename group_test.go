@@ -0,0 +1,168 @@
+package entitas
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	Convey("Given a group with a few entities", t, func() {
+		context := NewContext(0, NumComponents)
+		group := context.Group(AllOf(ComponentA))
+		var entities []Entity
+		for i := 0; i < 5; i++ {
+			entities = append(entities, context.CreateEntity(NewComponentA(i)))
+		}
+
+		Convey("Entities keep their order between calls", func() {
+			So(group.Entities(), ShouldResemble, entities)
+			So(group.Entities(), ShouldResemble, entities)
+		})
+
+		Convey("Removing moves the last entity into the free slot", func() {
+			entities[1].RemoveComponent(ComponentA)
+			So(group.Entities(), ShouldResemble, []Entity{entities[0], entities[4], entities[2], entities[3]})
+			So(group.ContainsEntity(entities[1]), ShouldBeFalse)
+			So(group.Count(), ShouldEqual, 4)
+		})
+
+		Convey("A slice handed out is not changed by later removals", func() {
+			iterated := 0
+			for _, e := range group.Entities() {
+				e.Destroy()
+				iterated++
+			}
+			So(iterated, ShouldEqual, 5)
+			So(group.Entities(), ShouldBeEmpty)
+		})
+	})
+}
+
+// mapGroup is the map based group storage the sparse set replaced, kept
+// to compare the two in benchmarks.
+type mapGroup struct {
+	entities map[EntityID]Entity
+	cache    []Entity
+}
+
+func (g *mapGroup) Entities() []Entity {
+	if g.cache == nil {
+		g.cache = make([]Entity, 0, len(g.entities))
+		for _, e := range g.entities {
+			g.cache = append(g.cache, e)
+		}
+	}
+	return g.cache
+}
+
+func (g *mapGroup) addEntity(e Entity, c Component) {
+	if _, ok := g.entities[e.ID()]; !ok {
+		g.entities[e.ID()] = e
+		if g.cache != nil {
+			g.cache = append(g.cache, e)
+		}
+	}
+}
+
+func (g *mapGroup) removeEntity(e Entity, c Component) {
+	if _, ok := g.entities[e.ID()]; ok {
+		delete(g.entities, e.ID())
+		g.cache = nil
+	}
+}
+
+type benchmarkGroup interface {
+	Entities() []Entity
+	addEntity(e Entity, c Component)
+	removeEntity(e Entity, c Component)
+}
+
+func benchmarkEntities() []Entity {
+	context := NewContext(0, NumComponents)
+	entities := make([]Entity, 1000)
+	for i := range entities {
+		entities[i] = context.CreateEntity()
+	}
+	return entities
+}
+
+func benchmarkGroupChurn(b *testing.B, g benchmarkGroup) {
+	entities := benchmarkEntities()
+	for _, e := range entities {
+		g.addEntity(e, nil)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e := entities[i%len(entities)]
+		g.removeEntity(e, nil)
+		g.addEntity(e, nil)
+		for range g.Entities() {
+		}
+	}
+}
+
+func benchmarkGroupIterate(b *testing.B, g benchmarkGroup) {
+	entities := benchmarkEntities()
+	for _, e := range entities {
+		g.addEntity(e, nil)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for range g.Entities() {
+		}
+	}
+}
+
+// benchmarkGroupRemoveWhileIterating removes every other entity while
+// iterating, as a system destroying what it processed does.
+func benchmarkGroupRemoveWhileIterating(b *testing.B, g benchmarkGroup) {
+	entities := benchmarkEntities()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		for _, e := range entities {
+			g.addEntity(e, nil)
+		}
+		b.StartTimer()
+		for j, e := range g.Entities() {
+			if j%2 == 0 {
+				g.removeEntity(e, nil)
+			}
+		}
+		b.StopTimer()
+		for _, e := range entities {
+			g.removeEntity(e, nil)
+		}
+		b.StartTimer()
+	}
+}
+
+func BenchmarkGroupChurn(b *testing.B) {
+	benchmarkGroupChurn(b, newGroup(nil).(*group))
+}
+
+func BenchmarkMapGroupChurn(b *testing.B) {
+	benchmarkGroupChurn(b, &mapGroup{entities: make(map[EntityID]Entity)})
+}
+
+func BenchmarkGroupIterate(b *testing.B) {
+	benchmarkGroupIterate(b, newGroup(nil).(*group))
+}
+
+func BenchmarkMapGroupIterate(b *testing.B) {
+	benchmarkGroupIterate(b, &mapGroup{entities: make(map[EntityID]Entity)})
+}
+
+func BenchmarkGroupRemoveWhileIterating(b *testing.B) {
+	benchmarkGroupRemoveWhileIterating(b, newGroup(nil).(*group))
+}
+
+func BenchmarkMapGroupRemoveWhileIterating(b *testing.B) {
+	benchmarkGroupRemoveWhileIterating(b, &mapGroup{entities: make(map[EntityID]Entity)})
+}
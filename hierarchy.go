@@ -0,0 +1,199 @@
+package entitas
+
+import "errors"
+
+var (
+	ErrHierarchyCycle = errors.New("entity would become its own ancestor")
+)
+
+// HierarchyPolicy decides what happens to the children of a destroyed
+// entity.
+type HierarchyPolicy uint
+
+const (
+	// HierarchyDestroyChildren destroys the children with their parent.
+	HierarchyDestroyChildren HierarchyPolicy = iota
+	// HierarchyDetachChildren keeps the children as roots.
+	HierarchyDetachChildren
+)
+
+// hierarchy links entities by handle, so a link never follows an entity ID
+// to the entity that reuses it.
+type hierarchy struct {
+	parents  map[EntityHandle]EntityHandle
+	children map[EntityHandle][]EntityHandle
+	policies map[EntityHandle]HierarchyPolicy
+}
+
+func newHierarchy() hierarchy {
+	return hierarchy{
+		parents:  make(map[EntityHandle]EntityHandle),
+		children: make(map[EntityHandle][]EntityHandle),
+		policies: make(map[EntityHandle]HierarchyPolicy),
+	}
+}
+
+// SetParent makes child a child of parent, after the children it already
+// has. A nil parent detaches child. It fires ContextEntityParentChanged for
+// child and ContextEntityChildrenChanged for its old and new parent.
+func (p *context) SetParent(child, parent Entity) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.setParent(child, parent)
+}
+
+func (p *context) Parent(e Entity) (Entity, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.parent(e)
+}
+
+// Children returns the children of e in the order they were attached.
+func (p *context) Children(e Entity) []Entity {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.children(e)
+}
+
+// Ancestors returns the parent of e, its parent and so on up to the root.
+func (p *context) Ancestors(e Entity) []Entity {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	var ancestors []Entity
+	for parent, ok := p.parent(e); ok; parent, ok = p.parent(parent) {
+		ancestors = append(ancestors, parent)
+	}
+	return ancestors
+}
+
+// SetHierarchyPolicy sets what happens to the children of e when it is
+// destroyed. Children are destroyed by default.
+func (p *context) SetHierarchyPolicy(e Entity, policy HierarchyPolicy) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.lock.beginWrite()
+	defer p.lock.endWrite()
+
+	if policy == HierarchyDestroyChildren {
+		delete(p.hierarchy.policies, e.Handle())
+	} else {
+		p.hierarchy.policies[e.Handle()] = policy
+	}
+}
+
+// private
+func (p *context) setParent(child, parent Entity) error {
+	if err := p.checkEntity(child); err != nil {
+		return err
+	}
+	if parent != nil {
+		if err := p.checkEntity(parent); err != nil {
+			return err
+		}
+		for h := parent.Handle(); ; {
+			if h == child.Handle() {
+				return ErrHierarchyCycle
+			}
+			var ok bool
+			if h, ok = p.hierarchy.parents[h]; !ok {
+				break
+			}
+		}
+	}
+
+	old, hasOld := p.parent(child)
+	if hasOld && old == parent || !hasOld && parent == nil {
+		return nil
+	}
+	p.lock.beginWrite()
+	if hasOld {
+		p.unlink(child.Handle(), old.Handle())
+	}
+	if parent != nil {
+		p.hierarchy.parents[child.Handle()] = parent.Handle()
+		p.hierarchy.children[parent.Handle()] = append(p.hierarchy.children[parent.Handle()], child.Handle())
+	}
+	p.lock.endWrite()
+
+	p.onEntityChanged(ContextEntityParentChanged, child)
+	if hasOld {
+		p.onEntityChanged(ContextEntityChildrenChanged, old)
+	}
+	if parent != nil {
+		p.onEntityChanged(ContextEntityChildrenChanged, parent)
+	}
+	return nil
+}
+
+func (p *context) parent(e Entity) (Entity, bool) {
+	h, ok := p.hierarchy.parents[e.Handle()]
+	if !ok {
+		return nil, false
+	}
+	return p.resolve(h)
+}
+
+func (p *context) children(e Entity) []Entity {
+	handles := p.hierarchy.children[e.Handle()]
+	children := make([]Entity, 0, len(handles))
+	for _, h := range handles {
+		if child, ok := p.resolve(h); ok {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// checkEntity returns ErrEntityDestroyed for a dead entity and panics for
+// an entity of another context.
+func (p *context) checkEntity(e Entity) error {
+	if p.hasEntity(e) {
+		return nil
+	}
+	if !e.IsAlive() {
+		return ErrEntityDestroyed
+	}
+	panic("unknown entity")
+}
+
+func (p *context) unlink(child, parent EntityHandle) {
+	delete(p.hierarchy.parents, child)
+	children := p.hierarchy.children[parent]
+	for i, h := range children {
+		if h == child {
+			children = append(children[:i:i], children[i+1:]...)
+			break
+		}
+	}
+	if len(children) == 0 {
+		delete(p.hierarchy.children, parent)
+	} else {
+		p.hierarchy.children[parent] = children
+	}
+}
+
+// destroyHierarchy runs before e is destroyed. It destroys or detaches the
+// children of e and detaches e from its parent.
+func (p *context) destroyHierarchy(e Entity) {
+	h := e.Handle()
+	policy := p.hierarchy.policies[h]
+	p.lock.beginWrite()
+	delete(p.hierarchy.policies, h)
+	p.lock.endWrite()
+
+	for _, child := range p.children(e) {
+		if policy == HierarchyDetachChildren {
+			p.setParent(child, nil)
+		} else {
+			p.destroyEntity(child)
+		}
+	}
+	if _, ok := p.hierarchy.parents[h]; ok {
+		p.setParent(e, nil)
+	}
+}
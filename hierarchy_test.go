@@ -0,0 +1,83 @@
+package entitas
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestHierarchy(t *testing.T) {
+	Convey("Given a root with a child and a grandchild", t, func() {
+		context := newSnapshotContext()
+		root := context.CreateEntity()
+		child := context.CreateEntity()
+		grandchild := context.CreateEntity()
+		So(context.SetParent(child, root), ShouldBeNil)
+		So(context.SetParent(grandchild, child), ShouldBeNil)
+
+		Convey("Links are readable both ways", func() {
+			parent, ok := context.Parent(grandchild)
+			So(ok, ShouldBeTrue)
+			So(parent, ShouldEqual, child)
+			So(context.Children(root), ShouldResemble, []Entity{child})
+			So(context.Ancestors(grandchild), ShouldResemble, []Entity{child, root})
+		})
+
+		Convey("Cycles are refused", func() {
+			So(context.SetParent(root, grandchild), ShouldEqual, ErrHierarchyCycle)
+			So(context.SetParent(root, root), ShouldEqual, ErrHierarchyCycle)
+		})
+
+		Convey("Reparenting fires events", func() {
+			var events []ContextEntityEvent
+			var entities []Entity
+			for _, ev := range []ContextEntityEvent{ContextEntityParentChanged, ContextEntityChildrenChanged} {
+				ev := ev
+				context.AddEvent(ev, func(c Context, e Entity) {
+					events = append(events, ev)
+					entities = append(entities, e)
+				})
+			}
+			So(context.SetParent(grandchild, root), ShouldBeNil)
+			So(events, ShouldResemble, []ContextEntityEvent{
+				ContextEntityParentChanged, ContextEntityChildrenChanged, ContextEntityChildrenChanged,
+			})
+			So(entities, ShouldResemble, []Entity{grandchild, child, root})
+			So(context.Children(root), ShouldResemble, []Entity{child, grandchild})
+		})
+
+		Convey("Destroying a parent destroys its children", func() {
+			root.Destroy()
+			So(child.IsAlive(), ShouldBeFalse)
+			So(grandchild.IsAlive(), ShouldBeFalse)
+		})
+
+		Convey("A parent may detach its children instead", func() {
+			context.SetHierarchyPolicy(child, HierarchyDetachChildren)
+			child.Destroy()
+			So(grandchild.IsAlive(), ShouldBeTrue)
+			_, ok := context.Parent(grandchild)
+			So(ok, ShouldBeFalse)
+			So(context.Children(root), ShouldBeEmpty)
+		})
+
+		Convey("Links do not follow a reused ID", func() {
+			grandchild.Destroy()
+			reused := context.CreateEntity()
+			So(reused.ID(), ShouldEqual, grandchild.ID())
+			So(context.Children(child), ShouldBeEmpty)
+			_, ok := context.Parent(reused)
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("Links survive a snapshot", func() {
+			var buf bytes.Buffer
+			So(context.Snapshot(&buf), ShouldBeNil)
+			restored := newSnapshotContext()
+			So(restored.Restore(&buf), ShouldBeNil)
+
+			e, _ := restored.Resolve(grandchild.Handle())
+			So(len(restored.Ancestors(e)), ShouldEqual, 2)
+		})
+	})
+}
@@ -0,0 +1,78 @@
+package entitas
+
+import "sync"
+
+// contextLock guards a context created WithConcurrency together with its
+// entities, groups, observers and indices. A nil *contextLock does nothing,
+// which is what contexts without concurrency use.
+//
+// A call that changes the context holds mu from start to end and calls
+// unexported helpers only, never a method that locks again. It takes data
+// for writing just while it stores a change, so the reads matchers and
+// index keys make in the middle of it take data for reading and go ahead.
+// Listeners and component hooks, which may call back into the context, are
+// queued with later and run once mu is released.
+type contextLock struct {
+	mu    sync.Mutex
+	data  sync.RWMutex
+	queue []func()
+}
+
+func (l *contextLock) Lock() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+}
+
+// Unlock releases mu and runs what was queued while it was held.
+func (l *contextLock) Unlock() {
+	if l == nil {
+		return
+	}
+	queue := l.queue
+	l.queue = nil
+	l.mu.Unlock()
+	for _, f := range queue {
+		f()
+	}
+}
+
+func (l *contextLock) RLock() {
+	if l == nil {
+		return
+	}
+	l.data.RLock()
+}
+
+func (l *contextLock) RUnlock() {
+	if l == nil {
+		return
+	}
+	l.data.RUnlock()
+}
+
+// beginWrite and endWrite bracket a store done while holding mu. Nothing
+// between them may call out of the package.
+func (l *contextLock) beginWrite() {
+	if l == nil {
+		return
+	}
+	l.data.Lock()
+}
+
+func (l *contextLock) endWrite() {
+	if l == nil {
+		return
+	}
+	l.data.Unlock()
+}
+
+// later runs f once mu is released, or right away without concurrency.
+func (l *contextLock) later(f func()) {
+	if l == nil {
+		f()
+		return
+	}
+	l.queue = append(l.queue, f)
+}
@@ -2,8 +2,10 @@ package entitas
 
 import (
 	"fmt"
+	"hash/fnv"
 	"reflect"
 	"sort"
+	"strings"
 )
 
 const (
@@ -12,6 +14,7 @@ const (
 	anyHashFactor            = 659
 	noneHashFactor           = 661
 	arrayHashFactor          = 673
+	whereHashFactor          = 677
 )
 
 type Matcher interface {
@@ -22,6 +25,12 @@ type Matcher interface {
 	String() string
 }
 
+// compoundable is implemented by the matchers that fold into one
+// CompoundMatcher.
+type compoundable interface {
+	parts() (all, any, none []int)
+}
+
 // baseMatcher
 type baseMatcher struct {
 	types []int
@@ -29,18 +38,7 @@ type baseMatcher struct {
 }
 
 func newBaseMatcher(types ...int) baseMatcher {
-	mtype := make(map[int]bool)
-	for _, t := range types {
-		mtype[t] = true
-	}
-
-	types = make([]int, 0, len(mtype))
-	for t := range mtype {
-		types = append(types, t)
-	}
-	sort.IntsAreSorted(types)
-
-	return baseMatcher{types: types}
+	return baseMatcher{types: typeSet(types)}
 }
 
 func (b *baseMatcher) Hash() uint {
@@ -56,7 +54,7 @@ type AllMatcher struct {
 	baseMatcher
 }
 
-func AllOf(types ...int) Matcher {
+func AllOf(types ...int) *AllMatcher {
 	b := newBaseMatcher(types...)
 	b.hash = Hash(allHashFactor, b.ComponentTypes()...)
 	return &AllMatcher{b}
@@ -70,8 +68,20 @@ func (a *AllMatcher) String() string {
 	return fmt.Sprintf("AllOf(%v)", print(a.ComponentTypes()...))
 }
 
-func (a *baseMatcher) Equals(m Matcher) bool {
-	return reflect.DeepEqual(a, m)
+func (a *AllMatcher) Equals(m Matcher) bool {
+	return equalParts(a, m)
+}
+
+func (a *AllMatcher) AnyOf(types ...int) *CompoundMatcher {
+	return newCompoundMatcher(a.types, types, nil)
+}
+
+func (a *AllMatcher) NoneOf(types ...int) *CompoundMatcher {
+	return newCompoundMatcher(a.types, nil, types)
+}
+
+func (a *AllMatcher) parts() (all, any, none []int) {
+	return a.types, nil, nil
 }
 
 // AnyOf
@@ -79,7 +89,7 @@ type AnyMatcher struct {
 	baseMatcher
 }
 
-func AnyOf(types ...int) Matcher {
+func AnyOf(types ...int) *AnyMatcher {
 	b := newBaseMatcher(types...)
 	b.hash = Hash(anyHashFactor, b.ComponentTypes()...)
 	return &AnyMatcher{b}
@@ -94,15 +104,27 @@ func (a *AnyMatcher) String() string {
 }
 
 func (a *AnyMatcher) Equals(m Matcher) bool {
-	return reflect.DeepEqual(a, m)
+	return equalParts(a, m)
+}
+
+func (a *AnyMatcher) AllOf(types ...int) *CompoundMatcher {
+	return newCompoundMatcher(types, a.types, nil)
+}
+
+func (a *AnyMatcher) NoneOf(types ...int) *CompoundMatcher {
+	return newCompoundMatcher(nil, a.types, types)
+}
+
+func (a *AnyMatcher) parts() (all, any, none []int) {
+	return nil, a.types, nil
 }
 
-// NonoOf
+// NoneOf
 type NoneMatcher struct {
 	baseMatcher
 }
 
-func NoneOf(types ...int) Matcher {
+func NoneOf(types ...int) *NoneMatcher {
 	b := newBaseMatcher(types...)
 	b.hash = Hash(noneHashFactor, b.ComponentTypes()...)
 	return &NoneMatcher{b}
@@ -113,21 +135,161 @@ func (n *NoneMatcher) Matches(e Entity) bool {
 }
 
 func (n *NoneMatcher) String() string {
-	return fmt.Sprintf("NonoOf(%v)", print(n.ComponentTypes()...))
+	return fmt.Sprintf("NoneOf(%v)", print(n.ComponentTypes()...))
 }
 
 func (n *NoneMatcher) Equals(m Matcher) bool {
-	return reflect.DeepEqual(n, m)
+	return equalParts(n, m)
+}
+
+func (n *NoneMatcher) AllOf(types ...int) *CompoundMatcher {
+	return newCompoundMatcher(types, nil, n.types)
+}
+
+func (n *NoneMatcher) AnyOf(types ...int) *CompoundMatcher {
+	return newCompoundMatcher(nil, types, n.types)
+}
+
+func (n *NoneMatcher) parts() (all, any, none []int) {
+	return nil, nil, n.types
+}
+
+// CompoundMatcher matches entities that have all of its AllOf types, at
+// least one of its AnyOf types if it has any, and none of its NoneOf types.
+// It is built by chaining, as in AllOf(a, b).AnyOf(c, d).NoneOf(e); calling
+// the same step twice adds to its types.
+type CompoundMatcher struct {
+	all   []int
+	any   []int
+	none  []int
+	types []int
+	hash  uint
+}
+
+func newCompoundMatcher(all, any, none []int) *CompoundMatcher {
+	c := &CompoundMatcher{
+		all:  typeSet(all),
+		any:  typeSet(any),
+		none: typeSet(none),
+	}
+
+	types := make([]int, 0, len(c.all)+len(c.any)+len(c.none))
+	types = append(append(append(types, c.all...), c.any...), c.none...)
+	c.types = typeSet(types)
+
+	// A part hashes like the matcher of its kind, so a compound of a single
+	// part has the same hash as that matcher. Parts are combined in order,
+	// so moving a type from one part to another changes the hash.
+	first := true
+	for _, part := range []struct {
+		factor uint
+		types  []int
+	}{{allHashFactor, c.all}, {anyHashFactor, c.any}, {noneHashFactor, c.none}} {
+		if len(part.types) == 0 {
+			continue
+		}
+		if first {
+			c.hash = Hash(part.factor, part.types...)
+			first = false
+		} else {
+			c.hash = c.hash*arrayHashFactor + Hash(part.factor, part.types...)
+		}
+	}
+	return c
+}
+
+func (c *CompoundMatcher) Matches(e Entity) bool {
+	return e.HasComponent(c.all...) &&
+		(len(c.any) == 0 || e.HasAnyComponent(c.any...)) &&
+		!e.HasAnyComponent(c.none...)
+}
+
+func (c *CompoundMatcher) Hash() uint {
+	return c.hash
+}
+
+func (c *CompoundMatcher) ComponentTypes() []int {
+	return c.types
+}
+
+func (c *CompoundMatcher) Equals(m Matcher) bool {
+	return equalParts(c, m)
+}
+
+func (c *CompoundMatcher) String() string {
+	var parts []string
+	if len(c.all) > 0 {
+		parts = append(parts, fmt.Sprintf("AllOf(%v)", print(c.all...)))
+	}
+	if len(c.any) > 0 {
+		parts = append(parts, fmt.Sprintf("AnyOf(%v)", print(c.any...)))
+	}
+	if len(c.none) > 0 {
+		parts = append(parts, fmt.Sprintf("NoneOf(%v)", print(c.none...)))
+	}
+	return strings.Join(parts, ".")
+}
+
+func (c *CompoundMatcher) AllOf(types ...int) *CompoundMatcher {
+	return newCompoundMatcher(append(append([]int(nil), c.all...), types...), c.any, c.none)
+}
+
+func (c *CompoundMatcher) AnyOf(types ...int) *CompoundMatcher {
+	return newCompoundMatcher(c.all, append(append([]int(nil), c.any...), types...), c.none)
+}
+
+func (c *CompoundMatcher) NoneOf(types ...int) *CompoundMatcher {
+	return newCompoundMatcher(c.all, c.any, append(append([]int(nil), c.none...), types...))
+}
+
+func (c *CompoundMatcher) parts() (all, any, none []int) {
+	return c.all, c.any, c.none
+}
+
+// Where
+type WhereMatcher struct {
+	baseMatcher
+	name      string
+	predicate func(Entity) bool
+}
+
+// Where matches entities that have all of types and satisfy predicate.
+// Groups re-check it whenever one of types is updated, so change component
+// values through UpdateComponent. Predicates can't be compared, so name
+// identifies one: Where matchers with the same name and types are equal
+// and share a group.
+func Where(name string, predicate func(Entity) bool, types ...int) *WhereMatcher {
+	b := newBaseMatcher(types...)
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	b.hash = Hash(whereHashFactor, b.ComponentTypes()...) ^ uint(h.Sum32())
+	return &WhereMatcher{b, name, predicate}
+}
+
+func (w *WhereMatcher) Matches(e Entity) bool {
+	return e.HasComponent(w.ComponentTypes()...) && w.predicate(e)
+}
+
+func (w *WhereMatcher) String() string {
+	return fmt.Sprintf("Where(%s, %v)", w.name, print(w.ComponentTypes()...))
+}
+
+func (w *WhereMatcher) Equals(m Matcher) bool {
+	other, ok := m.(*WhereMatcher)
+	return ok && other.name == w.name && equalTypes(other.types, w.types)
 }
 
 // Utilities
+
+// Hash combines factor and types in order. Matchers pass their types
+// sorted, so equal type sets hash alike. Different matchers may still
+// share a hash; contexts tell them apart with Equals.
 func Hash(factor uint, types ...int) uint {
-	var hash uint
+	hash := factor
 	for _, t := range types {
-		hash ^= uint(t) * componentHashFactor
+		hash = hash*componentHashFactor + uint(t) + 1
 	}
-	hash ^= uint(len(types)) * factor
-	return uint(hash)
+	return hash*componentHashFactor + uint(len(types))
 }
 
 func HashMatcher(matchers ...Matcher) uint {
@@ -143,6 +305,99 @@ func HashMatcher(matchers ...Matcher) uint {
 	return hash
 }
 
+// compoundMatchers folds the AllOf, AnyOf, NoneOf and compound matchers of
+// a group into one CompoundMatcher, so equivalent ways of writing a group
+// find the same group. AnyOf matchers are only folded when there is at
+// most one of them, since two of them require a type of each, and an empty
+// AnyOf, which matches nothing, is never folded.
+func compoundMatchers(matchers []Matcher) []Matcher {
+	var all, any, none []int
+	rest := make([]Matcher, 0, len(matchers))
+	anys, compounds := 0, 0
+
+	for _, m := range matchers {
+		c, ok := m.(compoundable)
+		if !ok {
+			rest = append(rest, m)
+			continue
+		}
+		if emptyAnyOf(m) {
+			return matchers
+		}
+		a, y, n := c.parts()
+		all = append(all, a...)
+		none = append(none, n...)
+		if len(y) > 0 {
+			any = y
+			anys++
+		}
+		compounds++
+	}
+
+	if compounds == 0 || anys > 1 {
+		return matchers
+	}
+	return append([]Matcher{newCompoundMatcher(all, any, none)}, rest...)
+}
+
+// equalMatchers reports whether a and b hold equal matchers, in any order.
+func equalMatchers(a, b []Matcher) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	used := make([]bool, len(b))
+	for _, m := range a {
+		found := false
+		for i, other := range b {
+			if !used[i] && m.Equals(other) {
+				used[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func equalParts(a compoundable, m Matcher) bool {
+	b, ok := m.(compoundable)
+	if !ok || emptyAnyOf(a) != emptyAnyOf(m) {
+		return false
+	}
+	all1, any1, none1 := a.parts()
+	all2, any2, none2 := b.parts()
+	return equalTypes(all1, all2) && equalTypes(any1, any2) && equalTypes(none1, none2)
+}
+
+// emptyAnyOf reports whether m is AnyOf(), which has no parts like AllOf()
+// but matches no entity.
+func emptyAnyOf(m any) bool {
+	a, ok := m.(*AnyMatcher)
+	return ok && len(a.types) == 0
+}
+
+func equalTypes(a, b []int) bool {
+	return len(a) == len(b) && (len(a) == 0 || reflect.DeepEqual(a, b))
+}
+
+// typeSet returns types sorted and without duplicates.
+func typeSet(types []int) []int {
+	mtype := make(map[int]bool)
+	for _, t := range types {
+		mtype[t] = true
+	}
+
+	set := make([]int, 0, len(mtype))
+	for t := range mtype {
+		set = append(set, t)
+	}
+	sort.Ints(set)
+	return set
+}
+
 func print(types ...int) string {
 	return fmt.Sprintf("%v", types)
 }
@@ -5,6 +5,32 @@ import (
 	"testing"
 )
 
+// collidingMatcher matches entities with its type and hashes like every
+// other collidingMatcher.
+type collidingMatcher struct {
+	t int
+}
+
+func (m collidingMatcher) Matches(e Entity) bool {
+	return e.HasComponent(m.t)
+}
+
+func (m collidingMatcher) Hash() uint {
+	return 1
+}
+
+func (m collidingMatcher) ComponentTypes() []int {
+	return []int{m.t}
+}
+
+func (m collidingMatcher) Equals(other Matcher) bool {
+	return other == Matcher(m)
+}
+
+func (m collidingMatcher) String() string {
+	return "colliding"
+}
+
 func TestMatcher(t *testing.T) {
 	Convey("Subject: Matchers", t, func() {
 		Convey("Given a few entities", func() {
@@ -20,5 +46,108 @@ func TestMatcher(t *testing.T) {
 			any2 := AnyOf(int(2), int(1), int(3), int(3))
 			So(any1.Equals(any2), ShouldBeTrue)
 		})
+
+		Convey("Compound matchers combine AllOf, AnyOf and NoneOf", func() {
+			m := AllOf(ComponentA).AnyOf(ComponentB, ComponentC).NoneOf(ComponentD)
+			So(m.String(), ShouldEqual, "AllOf([0]).AnyOf([1 2]).NoneOf([3])")
+			So(m.ComponentTypes(), ShouldResemble, []int{ComponentA, ComponentB, ComponentC, ComponentD})
+
+			context := NewContext(0, NumComponents)
+			So(m.Matches(context.CreateEntity(NewComponentA(0), NewComponentC())), ShouldBeTrue)
+			So(m.Matches(context.CreateEntity(NewComponentA(0))), ShouldBeFalse)
+			So(m.Matches(context.CreateEntity(NewComponentA(0), NewComponentB(0), NewComponentD())), ShouldBeFalse)
+		})
+
+		Convey("Equivalent compound matchers are equal", func() {
+			m1 := AllOf(ComponentA).NoneOf(ComponentB)
+			m2 := NoneOf(ComponentB).AllOf(ComponentA)
+			So(m1.Equals(m2), ShouldBeTrue)
+			So(m1.Hash(), ShouldEqual, m2.Hash())
+			So(m1.Equals(AllOf(ComponentA)), ShouldBeFalse)
+			So(AllOf(ComponentA).Equals(AllOf(ComponentA).AnyOf()), ShouldBeTrue)
+		})
+
+		Convey("Equivalent matchers find the same group", func() {
+			context := NewContext(0, NumComponents)
+			g := context.Group(AllOf(ComponentA).AnyOf(ComponentB).NoneOf(ComponentC))
+			So(context.Group(NoneOf(ComponentC).AnyOf(ComponentB).AllOf(ComponentA)), ShouldEqual, g)
+			So(context.Group(AllOf(ComponentA), AnyOf(ComponentB), NoneOf(ComponentC)), ShouldEqual, g)
+			So(context.Group(AllOf(ComponentA)), ShouldNotEqual, g)
+
+			Convey("Matchers that move types between parts differ", func() {
+				So(AllOf(ComponentA).NoneOf(ComponentB).Hash(), ShouldNotEqual, AllOf(ComponentB).NoneOf(ComponentA).Hash())
+				So(context.Group(AllOf(ComponentA).NoneOf(ComponentB)), ShouldNotEqual, context.Group(AllOf(ComponentB).NoneOf(ComponentA)))
+				So(context.Group(AllOf(ComponentA, ComponentC)), ShouldNotEqual, context.Group(AnyOf(ComponentB)))
+			})
+
+			Convey("Groups follow every type of the compound matcher", func() {
+				e := context.CreateEntity(NewComponentA(0), NewComponentB(0))
+				So(g.ContainsEntity(e), ShouldBeTrue)
+				e.AddComponent(NewComponentC())
+				So(g.ContainsEntity(e), ShouldBeFalse)
+			})
+
+			Convey("An empty AnyOf matches no entity in a group either", func() {
+				e := context.CreateEntity(NewComponentA(0))
+				So(AnyOf().Matches(e), ShouldBeFalse)
+				So(AnyOf().Equals(AllOf()), ShouldBeFalse)
+				So(AllOf().Equals(AnyOf()), ShouldBeFalse)
+				So(context.Group(AnyOf()).ContainsEntity(e), ShouldBeFalse)
+				So(context.Group(AllOf(ComponentA), AnyOf()).ContainsEntity(e), ShouldBeFalse)
+				So(context.Group(AllOf(ComponentA), AnyOf()), ShouldNotEqual, context.Group(AllOf(ComponentA)))
+			})
+		})
+	})
+}
+
+func TestWhereMatcher(t *testing.T) {
+	Convey("Given a group of entities whose component A value is negative", t, func() {
+		context := NewContext(0, NumComponents)
+		negative := func(e Entity) bool {
+			c, _ := e.Component(ComponentA)
+			return c.(*componentA).value < 0
+		}
+		g := context.Group(Where("negative", negative, ComponentA))
+		e := context.CreateEntity(NewComponentA(1))
+
+		Convey("Membership follows component updates", func() {
+			So(g.ContainsEntity(e), ShouldBeFalse)
+
+			e.UpdateComponent(NewComponentA(-1))
+			So(g.ContainsEntity(e), ShouldBeTrue)
+
+			c, _ := e.Component(ComponentA)
+			c.(*componentA).value = 2
+			e.UpdateComponent(c)
+			So(g.ContainsEntity(e), ShouldBeFalse)
+		})
+
+		Convey("Matchers with the same name share the group", func() {
+			So(context.Group(Where("negative", negative, ComponentA)), ShouldEqual, g)
+			So(context.Group(Where("positive", negative, ComponentA)), ShouldNotEqual, g)
+		})
+
+		Convey("It combines with other matchers", func() {
+			both := context.Group(Where("negative", negative, ComponentA), NoneOf(ComponentB))
+			e.UpdateComponent(NewComponentA(-1))
+			So(both.ContainsEntity(e), ShouldBeTrue)
+			e.AddComponent(NewComponentB(0))
+			So(both.ContainsEntity(e), ShouldBeFalse)
+		})
+
+		Convey("Matchers with the same hash get their own groups", func() {
+			context := NewContext(0, NumComponents)
+			a := context.Group(collidingMatcher{ComponentA})
+			b := context.Group(collidingMatcher{ComponentB})
+			So(b, ShouldNotEqual, a)
+			So(context.Group(collidingMatcher{ComponentA}), ShouldEqual, a)
+
+			e := context.CreateEntity(NewComponentA(0))
+			So(a.ContainsEntity(e), ShouldBeTrue)
+			So(b.ContainsEntity(e), ShouldBeFalse)
+
+			context.ReleaseGroup(b)
+			So(context.Groups(), ShouldResemble, []Group{a})
+		})
 	})
 }
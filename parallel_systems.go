@@ -0,0 +1,258 @@
+package entitas
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ComponentAccess is implemented by systems that declare which component
+// types they read and which they write. A system without a declaration is
+// treated as touching everything and never runs next to another system.
+type ComponentAccess interface {
+	Reads() []int
+	Writes() []int
+}
+
+// UndeclaredAccess is an access to a component type a system did not
+// declare, found by ParallelSystems in debug mode.
+type UndeclaredAccess struct {
+	System System
+	Type   int
+	Write  bool
+}
+
+func (u UndeclaredAccess) String() string {
+	access := "read"
+	if u.Write {
+		access = "write"
+	}
+	return fmt.Sprintf("%T: undeclared %s of component type %d", u.System, access, u.Type)
+}
+
+type access struct {
+	all    bool
+	reads  map[int]bool
+	writes map[int]bool
+}
+
+func newAccess(s System) access {
+	declared, ok := s.(ComponentAccess)
+	if !ok {
+		return access{all: true}
+	}
+
+	a := access{
+		reads:  make(map[int]bool),
+		writes: make(map[int]bool),
+	}
+	for _, t := range declared.Reads() {
+		a.reads[t] = true
+	}
+	for _, t := range declared.Writes() {
+		a.writes[t] = true
+	}
+	return a
+}
+
+func (a access) conflicts(b access) bool {
+	if a.all || b.all {
+		return true
+	}
+	for t := range a.writes {
+		if b.reads[t] || b.writes[t] {
+			return true
+		}
+	}
+	for t := range b.writes {
+		if a.reads[t] {
+			return true
+		}
+	}
+	return false
+}
+
+func (a access) allows(t int, write bool) bool {
+	if a.all || a.writes[t] {
+		return true
+	}
+	return !write && a.reads[t]
+}
+
+// ParallelSystems executes its children on a pool of workers. A child waits
+// for every child added before it whose declared access conflicts with its
+// own, so the result is the same as running them in order.
+//
+// Children running at the same time may read entities, groups and the
+// context, which never writes on reads, and may only change component
+// values in place. Adding, replacing or removing components and creating
+// or destroying entities updates shared groups and must not happen while
+// they run, unless the context was created WithConcurrency.
+type ParallelSystems struct {
+	name    string
+	workers int
+	systems []System
+	access  []access
+
+	// dependents[i] are the children that wait for child i, and
+	// dependencies[i] is how many children child i waits for.
+	dependents   [][]int
+	dependencies []int
+	dirty        bool
+
+	context Context
+	report  func(UndeclaredAccess)
+}
+
+func NewParallelSystems(name string, workers int) *ParallelSystems {
+	if workers < 1 {
+		workers = 1
+	}
+	return &ParallelSystems{name: name, workers: workers}
+}
+
+func (ps *ParallelSystems) Name() string {
+	return ps.name
+}
+
+func (ps *ParallelSystems) Add(s System) *ParallelSystems {
+	ps.systems = append(ps.systems, s)
+	ps.access = append(ps.access, newAccess(s))
+	ps.dirty = true
+	return ps
+}
+
+// SetDebug runs the children one by one and calls report for every
+// component access a child makes outside of what it declared. Reads of a
+// component through a pointer obtained earlier can't be seen. A nil report
+// turns debug mode off.
+func (ps *ParallelSystems) SetDebug(report func(UndeclaredAccess)) {
+	ps.report = report
+}
+
+func (ps *ParallelSystems) Initialize(context Context) {
+	ps.context = context
+	for _, s := range ps.systems {
+		if system, ok := s.(InitializeSystem); ok {
+			system.Initialize(context)
+		}
+	}
+}
+
+func (ps *ParallelSystems) Execute() {
+	if ps.report != nil {
+		ps.executeDebug()
+		return
+	}
+	if ps.dirty {
+		ps.build()
+	}
+
+	dependencies := make([]int, len(ps.systems))
+	copy(dependencies, ps.dependencies)
+
+	ready := make(chan int, len(ps.systems))
+	done := make(chan int, len(ps.systems))
+
+	var wg sync.WaitGroup
+	for w := 0; w < ps.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range ready {
+				if system, ok := ps.systems[i].(ExecuteSystem); ok {
+					system.Execute()
+				}
+				done <- i
+			}
+		}()
+	}
+
+	for i, n := range dependencies {
+		if n == 0 {
+			ready <- i
+		}
+	}
+	for finished := 0; finished < len(ps.systems); finished++ {
+		i := <-done
+		for _, j := range ps.dependents[i] {
+			dependencies[j]--
+			if dependencies[j] == 0 {
+				ready <- j
+			}
+		}
+	}
+	close(ready)
+	wg.Wait()
+}
+
+func (ps *ParallelSystems) Cleanup() {
+	for _, s := range ps.systems {
+		if system, ok := s.(CleanupSystem); ok {
+			system.Cleanup()
+		}
+	}
+}
+
+func (ps *ParallelSystems) TearDown() {
+	for _, s := range ps.systems {
+		if system, ok := s.(TearDownSystem); ok {
+			system.TearDown()
+		}
+	}
+}
+
+// private
+func (ps *ParallelSystems) build() {
+	ps.dependents = make([][]int, len(ps.systems))
+	ps.dependencies = make([]int, len(ps.systems))
+
+	for j := range ps.systems {
+		for i := 0; i < j; i++ {
+			if ps.access[i].conflicts(ps.access[j]) {
+				ps.dependents[i] = append(ps.dependents[i], j)
+				ps.dependencies[j]++
+			}
+		}
+	}
+	ps.dirty = false
+}
+
+func (ps *ParallelSystems) executeDebug() {
+	for i, s := range ps.systems {
+		system, ok := s.(ExecuteSystem)
+		if !ok {
+			continue
+		}
+
+		var reports []UndeclaredAccess
+		if ps.context != nil {
+			a := ps.access[i]
+			seen := make(map[[2]int]bool)
+			ps.context.setAccessHook(func(t int, write bool) {
+				key := [2]int{t, 0}
+				if write {
+					key[1] = 1
+				}
+				if !a.allows(t, write) && !seen[key] {
+					seen[key] = true
+					reports = append(reports, UndeclaredAccess{s, t, write})
+				}
+			})
+		}
+		system.Execute()
+		if ps.context != nil {
+			ps.context.setAccessHook(nil)
+		}
+
+		sort.Slice(reports, func(a, b int) bool {
+			if reports[a].Type != reports[b].Type {
+				return reports[a].Type < reports[b].Type
+			}
+			return !reports[a].Write && reports[b].Write
+		})
+		for _, u := range reports {
+			ps.report(u)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package entitas
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testAccessSystem struct {
+	reads, writes []int
+	execute       func()
+}
+
+func (s *testAccessSystem) Reads() []int {
+	return s.reads
+}
+
+func (s *testAccessSystem) Writes() []int {
+	return s.writes
+}
+
+func (s *testAccessSystem) Execute() {
+	s.execute()
+}
+
+func TestParallelSystems(t *testing.T) {
+	Convey("Given parallel systems with two workers", t, func() {
+		context := NewContext(0, NumComponents)
+		systems := NewParallelSystems("parallel", 2)
+
+		Convey("Systems without conflicts run at the same time", func() {
+			var barrier sync.WaitGroup
+			barrier.Add(2)
+			overlapped := make(chan bool, 2)
+			wait := func() {
+				barrier.Done()
+				met := make(chan bool)
+				go func() {
+					barrier.Wait()
+					close(met)
+				}()
+				select {
+				case <-met:
+					overlapped <- true
+				case <-time.After(time.Second):
+					overlapped <- false
+				}
+			}
+			systems.Add(&testAccessSystem{[]int{ComponentA}, []int{ComponentB}, wait})
+			systems.Add(&testAccessSystem{[]int{ComponentA}, []int{ComponentC}, wait})
+			systems.Initialize(context)
+			systems.Execute()
+
+			So(<-overlapped, ShouldBeTrue)
+			So(<-overlapped, ShouldBeTrue)
+		})
+
+		Convey("Readers running at the same time don't write shared state", func() {
+			group := context.Group(AllOf(ComponentA))
+			for i := 0; i < 10; i++ {
+				context.CreateEntity(NewComponentA(i), NewComponentB(float32(i)))
+			}
+			var barrier sync.WaitGroup
+			barrier.Add(2)
+			read := func() {
+				barrier.Done()
+				barrier.Wait()
+				for _, e := range group.Entities() {
+					e.Components()
+					e.ComponentTypes()
+					e.Component(ComponentA)
+				}
+				context.Entities()
+				context.HasUnique(ComponentA)
+			}
+			systems.Add(&testAccessSystem{[]int{ComponentA, ComponentB}, nil, read})
+			systems.Add(&testAccessSystem{[]int{ComponentA, ComponentB}, nil, read})
+			systems.Initialize(context)
+			systems.Execute()
+
+			So(group.Count(), ShouldEqual, 10)
+		})
+
+		Convey("Conflicting systems keep the order they were added in", func() {
+			var mu sync.Mutex
+			var order []int
+			record := func(i int) func() {
+				return func() {
+					time.Sleep(time.Duration(3-i) * 5 * time.Millisecond)
+					mu.Lock()
+					order = append(order, i)
+					mu.Unlock()
+				}
+			}
+			systems.Add(&testAccessSystem{nil, []int{ComponentA}, record(0)})
+			systems.Add(&testAccessSystem{[]int{ComponentA}, nil, record(1)})
+			systems.Add(&testAccessSystem{nil, []int{ComponentA}, record(2)})
+			systems.Initialize(context)
+			systems.Execute()
+
+			So(order, ShouldResemble, []int{0, 1, 2})
+		})
+
+		Convey("Debug mode reports undeclared access", func() {
+			e := context.CreateEntity(NewComponentA(1))
+			s := &testAccessSystem{[]int{ComponentA}, nil, func() {
+				e.Component(ComponentA)
+				e.HasComponent(ComponentB)
+				e.AddComponent(NewComponentC())
+			}}
+			systems.Add(s)
+			context.Group(AllOf(ComponentA, ComponentC))
+			systems.Initialize(context)
+
+			var reports []UndeclaredAccess
+			systems.SetDebug(func(u UndeclaredAccess) {
+				reports = append(reports, u)
+			})
+			systems.Execute()
+
+			So(reports, ShouldResemble, []UndeclaredAccess{
+				{s, ComponentB, false},
+				{s, ComponentC, true},
+			})
+		})
+	})
+}
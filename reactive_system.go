@@ -0,0 +1,63 @@
+package entitas
+
+// ReactiveSystem executes only on the entities collected by its trigger.
+type ReactiveSystem interface {
+	GetTrigger(context Context) GroupObserver
+	Filter(entity Entity) bool
+	Execute(entities []Entity)
+}
+
+type reactiveSystem struct {
+	system   ReactiveSystem
+	context  Context
+	observer GroupObserver
+	buffer   []Entity
+	inactive bool
+}
+
+// NewReactiveSystem wraps system so it can run inside Systems. Every
+// Execute hands system the collected entities that still exist and pass
+// Filter. The trigger is cleared once they are collected, so entities
+// system changes itself are picked up again on the next Execute.
+func NewReactiveSystem(system ReactiveSystem) System {
+	return &reactiveSystem{system: system}
+}
+
+func (r *reactiveSystem) Initialize(context Context) {
+	r.context = context
+	r.observer = r.system.GetTrigger(context)
+	if r.inactive {
+		r.observer.Deactivate()
+	}
+}
+
+func (r *reactiveSystem) Execute() {
+	for _, e := range r.observer.CollectedEntities() {
+		if r.context.HasEntity(e) && r.system.Filter(e) {
+			r.buffer = append(r.buffer, e)
+		}
+	}
+	r.observer.ClearCollectedEntities()
+
+	if len(r.buffer) > 0 {
+		r.system.Execute(r.buffer)
+		r.buffer = r.buffer[:0]
+	}
+}
+
+// Activate and Deactivate may come before Initialize, which then starts the
+// trigger in the state last asked for.
+func (r *reactiveSystem) Activate() {
+	r.inactive = false
+	if r.observer != nil {
+		r.observer.Activate()
+	}
+}
+
+func (r *reactiveSystem) Deactivate() {
+	r.inactive = true
+	if r.observer != nil {
+		r.observer.Deactivate()
+		r.observer.ClearCollectedEntities()
+	}
+}
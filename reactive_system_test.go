@@ -0,0 +1,69 @@
+package entitas
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+type testReactiveSystem struct {
+	executed [][]Entity
+}
+
+func (s *testReactiveSystem) GetTrigger(context Context) GroupObserver {
+	return NewGroupObserver(context.Group(AllOf(ComponentA)), EventAdded)
+}
+
+func (s *testReactiveSystem) Filter(e Entity) bool {
+	return !e.HasComponent(ComponentB)
+}
+
+func (s *testReactiveSystem) Execute(entities []Entity) {
+	s.executed = append(s.executed, append([]Entity(nil), entities...))
+}
+
+func TestReactiveSystem(t *testing.T) {
+	Convey("Given a reactive system triggered by component A", t, func() {
+		context := NewContext(0, NumComponents)
+		rs := &testReactiveSystem{}
+		system := NewSystems("reactive").Add(NewReactiveSystem(rs))
+		system.Initialize(context)
+
+		Convey("It does not execute when nothing was collected", func() {
+			system.Execute()
+			So(rs.executed, ShouldBeEmpty)
+		})
+
+		Convey("It executes on collected, filtered and living entities once", func() {
+			e1 := context.CreateEntity(NewComponentA(1))
+			context.CreateEntity(NewComponentA(2), NewComponentB(0))
+			e3 := context.CreateEntity(NewComponentA(3))
+			e3.Destroy()
+
+			system.Execute()
+			So(rs.executed, ShouldResemble, [][]Entity{{e1}})
+
+			system.Execute()
+			So(len(rs.executed), ShouldEqual, 1)
+		})
+	})
+
+	Convey("Given a reactive system deactivated before it is initialized", t, func() {
+		context := NewContext(0, NumComponents)
+		rs := &testReactiveSystem{}
+		reactive := NewReactiveSystem(rs)
+		system := NewSystems("reactive").Add(reactive)
+		So(func() { system.DeactivateSystem(reactive) }, ShouldNotPanic)
+		system.Initialize(context)
+
+		Convey("It does not collect until it is activated", func() {
+			context.CreateEntity(NewComponentA(1))
+			system.ActivateSystem(reactive)
+			system.Execute()
+			So(rs.executed, ShouldBeEmpty)
+
+			e := context.CreateEntity(NewComponentA(2))
+			system.Execute()
+			So(rs.executed, ShouldResemble, [][]Entity{{e}})
+		})
+	})
+}
@@ -0,0 +1,168 @@
+package entitas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+var (
+	ErrContextNotEmpty = errors.New("context is not empty")
+)
+
+type contextSnapshot struct {
+	Index    EntityID         `json:"index"`
+	Entities []entitySnapshot `json:"entities"`
+}
+
+type entitySnapshot struct {
+	ID         EntityID            `json:"id"`
+	Generation uint32              `json:"generation"`
+	Unique     []string            `json:"unique,omitempty"`
+	Components []componentSnapshot `json:"components"`
+	Children   []EntityHandle      `json:"children,omitempty"`
+	Policy     HierarchyPolicy     `json:"policy,omitempty"`
+}
+
+type componentSnapshot struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Snapshot writes every entity of the context with its components as JSON.
+// Components are saved by the name of their registered type, so only
+// exported fields survive.
+func (p *context) Snapshot(w io.Writer) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	snapshot := contextSnapshot{
+		Index:    p.index,
+		Entities: make([]entitySnapshot, 0, len(p.entities)),
+	}
+
+	for _, e := range p.entities {
+		es := entitySnapshot{
+			ID:         e.ID(),
+			Generation: e.Handle().Generation,
+			Children:   p.hierarchy.children[e.Handle()],
+			Policy:     p.hierarchy.policies[e.Handle()],
+		}
+		for _, t := range e.ComponentTypes() {
+			if p.registerComponent[t] == nil {
+				return fmt.Errorf("%w: type %d", ErrComponentNotRegistered, t)
+			}
+			if p.uniqueEntity(t) == e {
+				es.Unique = append(es.Unique, componentName(p.registerComponent[t]))
+			}
+			c, _ := e.Component(t)
+			value, err := json.Marshal(c)
+			if err != nil {
+				return err
+			}
+			es.Components = append(es.Components, componentSnapshot{
+				Type:  componentName(p.registerComponent[t]),
+				Value: value,
+			})
+		}
+		snapshot.Entities = append(snapshot.Entities, es)
+	}
+
+	sort.Slice(snapshot.Entities, func(i, j int) bool {
+		return snapshot.Entities[i].ID < snapshot.Entities[j].ID
+	})
+
+	return json.NewEncoder(w).Encode(snapshot)
+}
+
+// Restore recreates the entities written by Snapshot in an empty context,
+// keeping their IDs, generations, unique components, hierarchy and the
+// next entity ID. Groups and indices of the context are filled as the
+// entities are created.
+func (p *context) Restore(r io.Reader) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.entities) > 0 || len(p.retained) > 0 {
+		return ErrContextNotEmpty
+	}
+
+	var snapshot contextSnapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return err
+	}
+
+	types := make(map[string]int)
+	for t, rt := range p.registerComponent {
+		if rt != nil {
+			types[componentName(rt)] = t
+		}
+	}
+
+	// Everything is decoded and checked before the first entity is created,
+	// so a snapshot that fails leaves the context empty.
+	type restoredEntity struct {
+		components []Component
+		unique     []int
+	}
+	restored := make([]restoredEntity, len(snapshot.Entities))
+	for i, es := range snapshot.Entities {
+		cs := make([]Component, 0, len(es.Components))
+		for _, cst := range es.Components {
+			t, ok := types[cst.Type]
+			if !ok {
+				return fmt.Errorf("%w: %s", ErrComponentNotRegistered, cst.Type)
+			}
+			value := reflect.New(p.registerComponent[t])
+			if err := json.Unmarshal(cst.Value, value.Interface()); err != nil {
+				return err
+			}
+			cs = append(cs, value.Interface().(Component))
+		}
+		restored[i].components = cs
+
+		for _, name := range es.Unique {
+			t, ok := types[name]
+			if !ok {
+				return fmt.Errorf("%w: %s", ErrComponentNotRegistered, name)
+			}
+			restored[i].unique = append(restored[i].unique, t)
+		}
+	}
+
+	for i, es := range snapshot.Entities {
+		e := newEntity(p, es.ID, es.Generation)
+		p.setupEntity(e)
+		p.addEntity(e, restored[i].components...)
+		for _, t := range restored[i].unique {
+			p.setUnique(t, e)
+		}
+	}
+
+	p.lock.beginWrite()
+	defer p.lock.endWrite()
+
+	for _, es := range snapshot.Entities {
+		parent := EntityHandle{es.ID, es.Generation}
+		if es.Policy != HierarchyDestroyChildren {
+			p.hierarchy.policies[parent] = es.Policy
+		}
+		if len(es.Children) > 0 {
+			p.hierarchy.children[parent] = es.Children
+		}
+		for _, child := range es.Children {
+			p.hierarchy.parents[child] = parent
+		}
+	}
+
+	p.unused = p.unused[:0]
+	p.index = snapshot.Index
+	return nil
+}
+
+func componentName(t reflect.Type) string {
+	return t.PkgPath() + "." + t.Name()
+}
@@ -0,0 +1,81 @@
+package entitas
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+type position struct {
+	X, Y int
+}
+
+func (p *position) Type() int {
+	return ComponentE
+}
+
+func newSnapshotContext() Context {
+	context := NewContext(0, NumComponents)
+	context.RegisterComponent(&position{})
+	context.RegisterComponent(&componentC{})
+	return context
+}
+
+func TestSnapshot(t *testing.T) {
+	Convey("Given a context with entities", t, func() {
+		context := newSnapshotContext()
+		e1 := context.CreateEntity(&position{1, 2}, NewComponentC())
+		context.CreateEntity().Destroy()
+		e3 := context.CreateEntity(&position{3, 4})
+
+		var buf bytes.Buffer
+		So(context.Snapshot(&buf), ShouldBeNil)
+
+		Convey("It is restored into a fresh context", func() {
+			restored := newSnapshotContext()
+			group := restored.Group(AllOf(ComponentE))
+			So(restored.Restore(&buf), ShouldBeNil)
+
+			So(restored.Count(), ShouldEqual, 2)
+			So(restored.IsAlive(e1.Handle()), ShouldBeTrue)
+			So(restored.IsAlive(e3.Handle()), ShouldBeTrue)
+			So(len(group.Entities()), ShouldEqual, 2)
+
+			e, _ := restored.Resolve(e3.Handle())
+			p, err := Get[*position](e)
+			So(err, ShouldBeNil)
+			So(*p, ShouldResemble, position{3, 4})
+
+			e, _ = restored.Resolve(e1.Handle())
+			So(e.HasComponent(ComponentC), ShouldBeTrue)
+
+			So(restored.CreateEntity().ID(), ShouldEqual, 2)
+		})
+
+		Convey("It is not restored into a context with entities", func() {
+			So(context.Restore(&buf), ShouldEqual, ErrContextNotEmpty)
+		})
+
+		Convey("Unknown component types are reported", func() {
+			restored := NewContext(0, NumComponents)
+			restored.RegisterComponent(&position{})
+			So(restored.Restore(&buf), ShouldNotBeNil)
+		})
+
+		Convey("A failed restore leaves the context empty", func() {
+			saved := newSnapshotContext()
+			saved.CreateEntity(&position{1, 2})
+			saved.CreateEntity(NewComponentC())
+			buf.Reset()
+			So(saved.Snapshot(&buf), ShouldBeNil)
+
+			restored := NewContext(0, NumComponents)
+			restored.RegisterComponent(&position{})
+			group := restored.Group(AllOf(ComponentE))
+			So(restored.Restore(&buf), ShouldNotBeNil)
+			So(restored.Count(), ShouldEqual, 0)
+			So(group.Count(), ShouldEqual, 0)
+			So(restored.CreateEntity().ID(), ShouldEqual, 0)
+		})
+	})
+}
@@ -1,26 +1,144 @@
 package entitas
 
-type System interface {
+// System is anything added to Systems. Which phases it takes part in is
+// decided by the phase interfaces below it implements.
+type System interface{}
+
+type InitializeSystem interface {
 	Initialize(context Context)
+}
+
+type ExecuteSystem interface {
 	Execute()
 }
 
+type CleanupSystem interface {
+	Cleanup()
+}
+
+type TearDownSystem interface {
+	TearDown()
+}
+
+// activatable is implemented by systems that hold resources while active,
+// such as reactive systems and their observers.
+type activatable interface {
+	Activate()
+	Deactivate()
+}
+
+type systemEntry struct {
+	system System
+	active bool
+}
+
+// Systems runs its children phase by phase in the order they were added.
+// Systems is a System itself, so features can be nested. A frame is one
+// Execute followed by one Cleanup, which makes every cleanup run after all
+// executes of the tree.
 type Systems struct {
-	systems []System
+	name    string
+	systems []*systemEntry
+}
+
+func NewSystems(name string) *Systems {
+	return &Systems{name: name}
+}
+
+func (ss *Systems) Name() string {
+	return ss.name
+}
+
+func (ss *Systems) Add(s System) *Systems {
+	ss.systems = append(ss.systems, &systemEntry{system: s, active: true})
+	return ss
+}
+
+func (ss *Systems) Initialize(context Context) {
+	for _, entry := range ss.systems {
+		if system, ok := entry.system.(InitializeSystem); ok {
+			system.Initialize(context)
+		}
+	}
+}
+
+func (ss *Systems) Execute() {
+	for _, entry := range ss.systems {
+		if system, ok := entry.system.(ExecuteSystem); ok && entry.active {
+			system.Execute()
+		}
+	}
+}
+
+func (ss *Systems) Cleanup() {
+	for _, entry := range ss.systems {
+		if system, ok := entry.system.(CleanupSystem); ok && entry.active {
+			system.Cleanup()
+		}
+	}
 }
 
-func (ss Systems) Add(s System) {
-	ss.systems = append(ss.systems, s)
+func (ss *Systems) TearDown() {
+	for _, entry := range ss.systems {
+		if system, ok := entry.system.(TearDownSystem); ok {
+			system.TearDown()
+		}
+	}
 }
 
-func (ss Systems) Initialize(context Context) {
-	for _, system := range ss.systems {
-		system.Initialize(context)
+// ActivateSystem resumes executing and cleaning up a child deactivated by
+// DeactivateSystem.
+func (ss *Systems) ActivateSystem(s System) {
+	if entry := ss.entry(s); entry != nil && !entry.active {
+		entry.active = true
+		if system, ok := s.(activatable); ok {
+			system.Activate()
+		}
 	}
 }
 
-func (ss Systems) Execute() {
-	for _, system := range ss.systems {
-		system.Execute()
+// DeactivateSystem skips a child in Execute and Cleanup until it is
+// activated again. Initialize and TearDown still reach it.
+func (ss *Systems) DeactivateSystem(s System) {
+	if entry := ss.entry(s); entry != nil && entry.active {
+		entry.active = false
+		if system, ok := s.(activatable); ok {
+			system.Deactivate()
+		}
+	}
+}
+
+// Activate activates the children of a feature that is activated again
+// inside its parent. Children deactivated on their own stay deactivated.
+func (ss *Systems) Activate() {
+	for _, entry := range ss.systems {
+		if system, ok := entry.system.(activatable); ok && entry.active {
+			system.Activate()
+		}
+	}
+}
+
+// Deactivate deactivates the active children of a feature deactivated
+// inside its parent, so nested reactive systems stop collecting.
+func (ss *Systems) Deactivate() {
+	for _, entry := range ss.systems {
+		if system, ok := entry.system.(activatable); ok && entry.active {
+			system.Deactivate()
+		}
+	}
+}
+
+func (ss *Systems) IsActive(s System) bool {
+	entry := ss.entry(s)
+	return entry != nil && entry.active
+}
+
+// private
+func (ss *Systems) entry(s System) *systemEntry {
+	for _, entry := range ss.systems {
+		if entry.system == s {
+			return entry
+		}
 	}
+	return nil
 }
@@ -0,0 +1,100 @@
+package entitas
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+type testSystem struct {
+	name  string
+	calls *[]string
+}
+
+func (s *testSystem) Initialize(context Context) {
+	*s.calls = append(*s.calls, s.name+".Initialize")
+}
+
+func (s *testSystem) Execute() {
+	*s.calls = append(*s.calls, s.name+".Execute")
+}
+
+func (s *testSystem) Cleanup() {
+	*s.calls = append(*s.calls, s.name+".Cleanup")
+}
+
+func (s *testSystem) TearDown() {
+	*s.calls = append(*s.calls, s.name+".TearDown")
+}
+
+type testExecuteSystem struct {
+	calls *[]string
+}
+
+func (s *testExecuteSystem) Execute() {
+	*s.calls = append(*s.calls, "e.Execute")
+}
+
+func TestSystems(t *testing.T) {
+	Convey("Given a feature nested in systems", t, func() {
+		context := NewContext(0, NumComponents)
+		calls := []string{}
+		a := &testSystem{"a", &calls}
+		b := &testSystem{"b", &calls}
+		feature := NewSystems("feature").Add(b)
+		systems := NewSystems("root").Add(a).Add(feature)
+
+		Convey("Every phase reaches every child in order", func() {
+			systems.Initialize(context)
+			systems.Execute()
+			systems.Cleanup()
+			systems.TearDown()
+
+			So(calls, ShouldResemble, []string{
+				"a.Initialize", "b.Initialize",
+				"a.Execute", "b.Execute",
+				"a.Cleanup", "b.Cleanup",
+				"a.TearDown", "b.TearDown",
+			})
+		})
+
+		Convey("A deactivated child skips execute and cleanup", func() {
+			systems.DeactivateSystem(feature)
+			So(systems.IsActive(feature), ShouldBeFalse)
+			systems.Execute()
+			systems.Cleanup()
+			So(calls, ShouldResemble, []string{"a.Execute", "a.Cleanup"})
+
+			systems.ActivateSystem(feature)
+			systems.Execute()
+			So(calls[len(calls)-1], ShouldEqual, "b.Execute")
+		})
+
+		Convey("Deactivating a feature deactivates its reactive children", func() {
+			rs := &testReactiveSystem{}
+			feature.Add(NewReactiveSystem(rs))
+			systems.Initialize(context)
+
+			systems.DeactivateSystem(feature)
+			e := context.CreateEntity(NewComponentA(1))
+			e.Destroy()
+			So(context.ReusableCount(), ShouldEqual, 1)
+
+			systems.ActivateSystem(feature)
+			systems.Execute()
+			So(rs.executed, ShouldBeEmpty)
+
+			e = context.CreateEntity(NewComponentA(2))
+			systems.Execute()
+			So(rs.executed, ShouldResemble, [][]Entity{{e}})
+		})
+
+		Convey("Systems only join the phases they implement", func() {
+			systems := NewSystems("root").Add(&testExecuteSystem{&calls})
+			systems.Initialize(context)
+			systems.Execute()
+			systems.Cleanup()
+			systems.TearDown()
+			So(calls, ShouldResemble, []string{"e.Execute"})
+		})
+	})
+}
@@ -21,8 +21,7 @@ func NewC1() entitas.Component {
 func main() {
 	fmt.Println(math.MaxUint32 / 30000)
 
-	entitas.TotalComponents = 99
-	context := entitas.NewContext(0)
+	context := entitas.NewContext(0, 99)
 	fmt.Println(context)
 
 	matcher := entitas.AllOf(1)
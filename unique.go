@@ -0,0 +1,110 @@
+package entitas
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUniqueComponentExists = errors.New("unique component exists")
+)
+
+// SetUnique creates an entity holding c, the only component of its type
+// the context keeps as unique. It fails if one is set already.
+func (p *context) SetUnique(c Component) (Entity, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.inRange(c.Type()) {
+		return nil, ErrComponentTypeOutOfRange
+	}
+	if p.uniqueEntity(c.Type()) != nil {
+		return nil, ErrUniqueComponentExists
+	}
+	e := p.addEntity(p.getEntity(), c)
+	p.setUnique(c.Type(), e)
+	return e, nil
+}
+
+// ReplaceUnique sets c as the unique component of its type, replacing the
+// current one or creating its entity. It panics if the type is out of the
+// context's range.
+func (p *context) ReplaceUnique(c Component) Entity {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.inRange(c.Type()) {
+		panic(fmt.Sprintf("component type %d out of range [0, %d)", c.Type(), p.totalComponents))
+	}
+	if e := p.uniqueEntity(c.Type()); e != nil {
+		e.updateComponent(c)
+		return e
+	}
+	e := p.addEntity(p.getEntity(), c)
+	p.setUnique(c.Type(), e)
+	return e
+}
+
+func (p *context) Unique(t int) (Component, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	e := p.uniqueEntity(t)
+	if e == nil {
+		return nil, ErrComponentDoesNotExist
+	}
+	p.componentAccessed(t, false)
+	return e.component(t), nil
+}
+
+// UniqueEntity returns the entity holding the unique component of type t.
+func (p *context) UniqueEntity(t int) (Entity, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	e := p.uniqueEntity(t)
+	return e, e != nil
+}
+
+func (p *context) HasUnique(t int) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.uniqueEntity(t) != nil
+}
+
+// RemoveUnique destroys the entity holding the unique component of type t.
+func (p *context) RemoveUnique(t int) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	e := p.uniqueEntity(t)
+	if e == nil {
+		return ErrComponentDoesNotExist
+	}
+	p.setUnique(t, nil)
+	p.destroyEntity(e)
+	return nil
+}
+
+// private
+
+// uniqueEntity returns the holder of unique type t, unless it was
+// destroyed or lost the component since. It leaves a stale holder in
+// place, so that reading never writes; the next SetUnique replaces it.
+func (p *context) uniqueEntity(t int) Entity {
+	if !p.inRange(t) {
+		return nil
+	}
+	e := p.uniques[t]
+	if e == nil || e.component(t) == nil {
+		return nil
+	}
+	return e
+}
+
+func (p *context) setUnique(t int, e Entity) {
+	p.lock.beginWrite()
+	p.uniques[t] = e
+	p.lock.endWrite()
+}
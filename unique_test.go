@@ -0,0 +1,68 @@
+package entitas
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestUnique(t *testing.T) {
+	Convey("Given a context with a unique position", t, func() {
+		context := newSnapshotContext()
+		holder, err := context.SetUnique(&position{1, 2})
+		So(err, ShouldBeNil)
+
+		Convey("It is found without a group", func() {
+			So(context.HasUnique(ComponentE), ShouldBeTrue)
+			p, err := GetUnique[*position](context)
+			So(err, ShouldBeNil)
+			So(*p, ShouldResemble, position{1, 2})
+
+			e, ok := context.UniqueEntity(ComponentE)
+			So(ok, ShouldBeTrue)
+			So(e, ShouldEqual, holder)
+		})
+
+		Convey("Setting a second one fails", func() {
+			_, err := context.SetUnique(&position{3, 4})
+			So(err, ShouldEqual, ErrUniqueComponentExists)
+		})
+
+		Convey("Replacing keeps the entity", func() {
+			So(context.ReplaceUnique(&position{3, 4}), ShouldEqual, holder)
+			c, _ := context.Unique(ComponentE)
+			So(*c.(*position), ShouldResemble, position{3, 4})
+		})
+
+		Convey("Removing destroys the entity", func() {
+			So(context.RemoveUnique(ComponentE), ShouldBeNil)
+			So(holder.IsAlive(), ShouldBeFalse)
+			So(context.HasUnique(ComponentE), ShouldBeFalse)
+			So(context.RemoveUnique(ComponentE), ShouldEqual, ErrComponentDoesNotExist)
+		})
+
+		Convey("A destroyed holder no longer counts", func() {
+			holder.Destroy()
+			_, err := context.Unique(ComponentE)
+			So(err, ShouldEqual, ErrComponentDoesNotExist)
+			_, err = context.SetUnique(&position{5, 6})
+			So(err, ShouldBeNil)
+		})
+
+		Convey("It survives a snapshot", func() {
+			holder.AddComponent(NewComponentC())
+
+			var buf bytes.Buffer
+			So(context.Snapshot(&buf), ShouldBeNil)
+			restored := newSnapshotContext()
+			So(restored.Restore(&buf), ShouldBeNil)
+			So(restored.HasUnique(ComponentE), ShouldBeTrue)
+
+			Convey("without making other components of its entity unique", func() {
+				So(restored.HasUnique(ComponentC), ShouldBeFalse)
+				_, err := restored.SetUnique(NewComponentC())
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+}